@@ -0,0 +1,129 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// Ownership and cost allocation labels the Function sets on composed
+// resources and the nodes they launch.
+const (
+	labelOwnerKind      = "nodepools.fn.crossplane.io/owner-kind"
+	labelOwnerName      = "nodepools.fn.crossplane.io/owner-name"
+	labelOwnerNamespace = "nodepools.fn.crossplane.io/owner-namespace"
+	labelEnvironment    = "nodepools.fn.crossplane.io/environment"
+	labelTeam           = "nodepools.fn.crossplane.io/team"
+	labelCostCenter     = "nodepools.fn.crossplane.io/cost-center"
+)
+
+// annotationOwnerAPIVersion is the annotation holding the owning XR's API
+// version, which isn't a valid label value.
+const annotationOwnerAPIVersion = "nodepools.fn.crossplane.io/owner-api-version"
+
+// Defaults of the Input's attribution.
+const (
+	defaultAttributionTeamField       = "spec.Team"
+	defaultAttributionCostCenterField = "spec.CostCenter"
+)
+
+// attributionLabels returns the labels that attribute a composed resource to
+// the supplied XR, its environment, team and cost center. It returns nil if a
+// is nil. The team and cost center are omitted if the XR doesn't set them.
+func attributionLabels(a *v1beta1.Attribution, xr *resource.Composite, environment string) (map[string]string, error) {
+	if a == nil {
+		return nil, nil
+	}
+	labels := map[string]string{
+		labelOwnerKind:   xr.Resource.GetKind(),
+		labelOwnerName:   xr.Resource.GetName(),
+		labelEnvironment: environment,
+	}
+	if ns := xrNamespace(xr); ns != "" {
+		labels[labelOwnerNamespace] = ns
+	}
+
+	fields := []struct {
+		path  string
+		label string
+	}{
+		{withDefault(a.TeamField, defaultAttributionTeamField), labelTeam},
+		{withDefault(a.CostCenterField, defaultAttributionCostCenterField), labelCostCenter},
+	}
+	for _, f := range fields {
+		v, err := xr.Resource.GetString(f.path)
+		if fieldpath.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s field of %s", f.path, xr.Resource.GetKind())
+		}
+		if v != "" {
+			labels[f.label] = v
+		}
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if errs := validation.IsValidLabelValue(labels[k]); len(errs) > 0 {
+			return nil, errors.Errorf("value %q of label %s is invalid: %s", labels[k], k, strings.Join(errs, "; "))
+		}
+	}
+	return labels, nil
+}
+
+// attribute sets the supplied attribution labels on the supplied NodePool,
+// NodeGroup or MachineDeployment, and on the nodes it launches. It tags
+// NodeGroups, and the NodeClasses the Function composes, with them too. EKS
+// doesn't copy a NodeGroup's tags to its instances, but Karpenter tags its
+// instances with their NodeClass's. It does nothing if labels is empty.
+func attribute(res *composed.Unstructured, xr *resource.Composite, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	res.SetLabels(mergeLabels(res.GetLabels(), labels))
+	res.SetAnnotations(mergeLabels(res.GetAnnotations(), map[string]string{annotationOwnerAPIVersion: xr.Resource.GetAPIVersion()}))
+
+	var paths []string
+	switch res.GetKind() {
+	case "NodeGroup":
+		paths = []string{"spec.forProvider.labels", "spec.forProvider.tags"}
+	case ec2NodeClassKind, autoModeNodeClassKind:
+		paths = []string{"spec.tags"}
+	default:
+		paths = []string{"spec.template.metadata.labels"}
+	}
+	for _, p := range paths {
+		existing := map[string]string{}
+		if err := res.GetValueInto(p, &existing); err != nil && !fieldpath.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot read %s of %s", p, res.GetKind())
+		}
+		if err := res.SetValue(p, mergeLabels(existing, labels)); err != nil {
+			return errors.Wrapf(err, "cannot set %s of %s", p, res.GetKind())
+		}
+	}
+	return nil
+}
+
+// mergeLabels returns the supplied labels with the supplied overrides.
+func mergeLabels(labels, overrides map[string]string) map[string]string {
+	out := make(map[string]string, len(labels)+len(overrides))
+	for k, v := range labels {
+		out[k] = v
+	}
+	for k, v := range overrides {
+		out[k] = v
+	}
+	return out
+}
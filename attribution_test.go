@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+	"github.com/google/go-cmp/cmp"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestAttributionLabels(t *testing.T) {
+	newXR := func(spec map[string]any) *resource.Composite {
+		xr := &resource.Composite{Resource: composite.New()}
+		xr.Resource.SetKind("XNodePool")
+		xr.Resource.SetName("np1")
+		xr.Resource.SetNamespace("payments")
+		xr.Resource.Object["spec"] = spec
+		return xr
+	}
+
+	type want struct {
+		labels map[string]string
+		err    string
+	}
+
+	cases := map[string]struct {
+		reason string
+		a      *v1beta1.Attribution
+		xr     *resource.Composite
+		want   want
+	}{
+		"NotConfigured": {
+			reason: "No labels should be returned if the Input doesn't configure attribution.",
+			xr:     newXR(map[string]any{"Team": "checkout"}),
+		},
+		"Defaults": {
+			reason: "The team and cost center should be read from their default fields.",
+			a:      &v1beta1.Attribution{},
+			xr:     newXR(map[string]any{"Team": "checkout", "CostCenter": "cc-4012"}),
+			want: want{labels: map[string]string{
+				labelOwnerKind:      "XNodePool",
+				labelOwnerName:      "np1",
+				labelOwnerNamespace: "payments",
+				labelEnvironment:    "production",
+				labelTeam:           "checkout",
+				labelCostCenter:     "cc-4012",
+			}},
+		},
+		"CustomFields": {
+			reason: "The team and cost center should be read from the configured fields, and omitted if the XR doesn't set them.",
+			a:      &v1beta1.Attribution{TeamField: "spec.owner.team"},
+			xr:     newXR(map[string]any{"Team": "ignored"}),
+			want: want{labels: map[string]string{
+				labelOwnerKind:      "XNodePool",
+				labelOwnerName:      "np1",
+				labelOwnerNamespace: "payments",
+				labelEnvironment:    "production",
+			}},
+		},
+		"NotAString": {
+			reason: "A team field that isn't a string should return an error.",
+			a:      &v1beta1.Attribution{},
+			xr:     newXR(map[string]any{"Team": int64(42)}),
+			want:   want{err: "cannot read spec.Team field of XNodePool: spec.Team: not a string"},
+		},
+		"InvalidValue": {
+			reason: "A cost center that isn't a valid label value should return an error.",
+			a:      &v1beta1.Attribution{},
+			xr:     newXR(map[string]any{"CostCenter": "Finance & Ops"}),
+			want:   want{err: `value "Finance & Ops" of label nodepools.fn.crossplane.io/cost-center is invalid: a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			labels, err := attributionLabels(tc.a, tc.xr, "production")
+			got := want{labels: labels}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nattributionLabels(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAttributeNodeGroup(t *testing.T) {
+	xr := &resource.Composite{Resource: composite.New()}
+	xr.Resource.SetAPIVersion("example.crossplane.io/v1alpha1")
+
+	ng, err := newNodeGroup("np1", "us-east-1", "cluster", &v1beta1.NodeGroup{Labels: map[string]string{"workload": "web"}}, []string{"m"}, []string{"m6i.xlarge"}, k8sresource.MustParse("8"), k8sresource.MustParse("32Gi"))
+	if err != nil {
+		t.Fatalf("newNodeGroup(...): %v", err)
+	}
+	if err := attribute(ng, xr, map[string]string{labelTeam: "checkout"}); err != nil {
+		t.Fatalf("attribute(...): %v", err)
+	}
+
+	want := map[string]any{
+		"labels": map[string]any{"workload": "web", labelTeam: "checkout"},
+		"tags":   map[string]any{labelTeam: "checkout"},
+	}
+	got := map[string]any{}
+	for _, f := range []string{"labels", "tags"} {
+		got[f], _ = ng.GetValue("spec.forProvider." + f)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("attribute(...): -want, +got spec.forProvider:\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{labelTeam: "checkout"}, ng.GetLabels()); diff != "" {
+		t.Errorf("attribute(...): -want, +got metadata.labels:\n%s", diff)
+	}
+	if diff := cmp.Diff("example.crossplane.io/v1alpha1", ng.GetAnnotations()[annotationOwnerAPIVersion]); diff != "" {
+		t.Errorf("attribute(...): -want, +got owner API version annotation:\n%s", diff)
+	}
+}
+
+func TestAttributeNodeClass(t *testing.T) {
+	xr := &resource.Composite{Resource: composite.New()}
+	xr.Resource.SetAPIVersion("example.crossplane.io/v1alpha1")
+
+	cases := map[string]struct {
+		reason string
+		new    func() (*composed.Unstructured, error)
+	}{
+		"EC2NodeClass": {
+			reason: "An EC2NodeClass should tag its instances with the attribution labels, as well as the Input's tags.",
+			new: func() (*composed.Unstructured, error) {
+				return newEC2NodeClass("np1", v1beta1.EC2NodeClass{Role: "node", Tags: map[string]string{"purpose": "web"}}, nil)
+			},
+		},
+		"AutoModeNodeClass": {
+			reason: "An EKS Auto Mode NodeClass should tag its instances with the attribution labels, as well as the Input's tags.",
+			new: func() (*composed.Unstructured, error) {
+				return newAutoModeNodeClass("np1", &v1beta1.AutoModeNodeClass{Role: "node", Tags: map[string]string{"purpose": "web"}})
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			nc, err := tc.new()
+			if err != nil {
+				t.Fatalf("\n%s\nnew NodeClass: %v", tc.reason, err)
+			}
+			if err := attribute(nc, xr, map[string]string{labelTeam: "checkout"}); err != nil {
+				t.Fatalf("\n%s\nattribute(...): %v", tc.reason, err)
+			}
+			got, _ := nc.GetValue("spec.tags")
+			if diff := cmp.Diff(map[string]any{"purpose": "web", labelTeam: "checkout"}, got); diff != "" {
+				t.Errorf("\n%s\nattribute(...): -want, +got spec.tags:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(map[string]string{labelTeam: "checkout"}, nc.GetLabels()); diff != "" {
+				t.Errorf("\n%s\nattribute(...): -want, +got metadata.labels:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
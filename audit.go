@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+	"github.com/crossplane/function-sdk-go/response"
+)
+
+// auditStatusField is the XR status field the Function writes audit reports to.
+const auditStatusField = "status.nodePoolAudit"
+
+// audit reports the NodePool, or NodeGroup, the Function would compose, and
+// how it differs from the supplied observed one, as results and XR status. It doesn't
+// add it to the desired composed resources; see keepObserved.
+func (f *Function) audit(rsp *fnv1.RunFunctionResponse, current map[string]any, nodePool *composed.Unstructured) error {
+	changes, err := diffFields(current, nodePool.UnstructuredContent())
+	if err != nil {
+		return errors.Wrapf(err, "cannot diff observed and desired %s", nodePool.GetKind())
+	}
+
+	response.Normalf(rsp, "Audit mode: would compose %s %q with spec %s", nodePool.GetKind(), nodePool.GetName(), jsonValue(nodePool.Object["spec"]))
+
+	reported := make([]any, len(changes))
+	described := make([]string, len(changes))
+	for i, c := range changes {
+		reported[i] = map[string]any{"path": c.Path, "observed": jsonValue(c.Old), "desired": jsonValue(c.New)}
+		described[i] = c.String()
+	}
+	switch {
+	case current == nil:
+		response.Normalf(rsp, "Audit mode: %s %q is not observed, composing it would create it", nodePool.GetKind(), nodePool.GetName())
+	case len(changes) == 0:
+		response.Normalf(rsp, "Audit mode: %s %q matches the observed %s", nodePool.GetKind(), nodePool.GetName(), nodePool.GetKind())
+	default:
+		response.Normalf(rsp, "Audit mode: composing %s %q would change %d field(s): %s", nodePool.GetKind(), nodePool.GetName(), len(changes), strings.Join(described, "; "))
+	}
+	f.log.Info("Audited composed resource", "kind", nodePool.GetKind(), "name", nodePool.GetName(), "changes", len(changes))
+
+	report := map[string]any{
+		"name":    nodePool.GetName(),
+		"spec":    nodePool.Object["spec"],
+		"changes": reported,
+	}
+	return setCompositeField(rsp, auditStatusField, report)
+}
+
+// keepObserved adds the supplied observed composed resources to the supplied
+// desired ones unless they're already desired. Crossplane deletes composed
+// resources that aren't desired, which an audit mustn't do. Only the fields
+// the Function may have composed are kept, not status or server set metadata.
+func keepObserved(desired map[resource.Name]*resource.DesiredComposed, observed map[resource.Name]resource.ObservedComposed) {
+	for name, oc := range observed {
+		if _, ok := desired[name]; ok {
+			continue
+		}
+		res := oc.Resource.DeepCopy()
+		delete(res.Object, "status")
+		res.Object["metadata"] = map[string]any{}
+		res.SetNamespace(oc.Resource.GetNamespace())
+		res.SetName(oc.Resource.GetName())
+		res.SetLabels(oc.Resource.GetLabels())
+		res.SetAnnotations(oc.Resource.GetAnnotations())
+		desired[name] = &resource.DesiredComposed{Resource: res}
+	}
+}
+
+// setCompositeField sets the supplied field of the response's desired
+// composite resource, keeping fields set earlier in the response.
+func setCompositeField(rsp *fnv1.RunFunctionResponse, path string, v any) error {
+	xr := composite.New()
+	if s := rsp.GetDesired().GetComposite().GetResource(); s != nil {
+		if err := resource.AsObject(s, xr); err != nil {
+			return errors.Wrapf(err, "cannot get desired composite resource from %T", rsp)
+		}
+	}
+	if err := xr.SetValue(path, v); err != nil {
+		return errors.Wrapf(err, "cannot set %s of desired composite resource", path)
+	}
+	return errors.Wrapf(response.SetDesiredCompositeResource(rsp, &resource.Composite{Resource: xr}), "cannot set desired composite resource in %T", rsp)
+}
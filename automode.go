@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// resourceNameNodeClass is the name of the composed NodeClass of a NodePool.
+const resourceNameNodeClass = resource.Name("nodeclass")
+
+// The EKS Auto Mode NodeClass API.
+const (
+	autoModeNodeClassAPIVersion = "eks.amazonaws.com/v1"
+	autoModeNodeClassKind       = "NodeClass"
+	autoModeDefaultNodeClass    = "default"
+)
+
+// autoModeProvider composes EKS Auto Mode NodePools. Auto Mode runs Karpenter
+// on AWS, but nodes are configured by eks.amazonaws.com NodeClasses and
+// labelled with eks.amazonaws.com labels.
+type autoModeProvider struct {
+	*awsProvider
+}
+
+// eksAutoMode returns a nodeProvider that composes EKS Auto Mode NodePools
+// with the supplied provider, which must be AWS.
+func eksAutoMode(prov nodeProvider) (nodeProvider, error) {
+	aws, ok := prov.(*awsProvider)
+	if !ok {
+		return nil, errors.Errorf("%s requires provider %q", autoscalerEKSAutoMode, providerAWS)
+	}
+	return &autoModeProvider{awsProvider: aws}, nil
+}
+
+// CategoryLabel is eks.amazonaws.com/instance-category.
+func (p *autoModeProvider) CategoryLabel() string { return "eks.amazonaws.com/instance-category" }
+
+// FamilyLabel is eks.amazonaws.com/instance-family.
+func (p *autoModeProvider) FamilyLabel() string { return "eks.amazonaws.com/instance-family" }
+
+// NodeClassRef returns the built in default NodeClass.
+func (p *autoModeProvider) NodeClassRef() *karpenterv1.NodeClassReference {
+	return &karpenterv1.NodeClassReference{
+		Group: "eks.amazonaws.com",
+		Kind:  autoModeNodeClassKind,
+		Name:  autoModeDefaultNodeClass,
+	}
+}
+
+// newAutoModeNodeClass returns an EKS Auto Mode NodeClass configured by the
+// supplied Input.
+func newAutoModeNodeClass(name string, cfg *v1beta1.AutoModeNodeClass) (*composed.Unstructured, error) {
+	spec := map[string]any{
+		"role": cfg.Role,
+	}
+	if terms := selectorTerms(cfg.SubnetSelectorTerms); len(terms) > 0 {
+		spec["subnetSelectorTerms"] = terms
+	}
+	if terms := selectorTerms(cfg.SecurityGroupSelectorTerms); len(terms) > 0 {
+		spec["securityGroupSelectorTerms"] = terms
+	}
+	if len(cfg.Tags) > 0 {
+		spec["tags"] = cfg.Tags
+	}
+
+	nc := composed.New()
+	nc.SetAPIVersion(autoModeNodeClassAPIVersion)
+	nc.SetKind(autoModeNodeClassKind)
+	nc.SetName(name)
+	if err := nc.SetValue("spec", spec); err != nil {
+		return nil, errors.Wrapf(err, "cannot set spec of %s", autoModeNodeClassKind)
+	}
+	return nc, nil
+}
+
+// selectorTerms returns the supplied selector terms as NodeClass fields.
+func selectorTerms(terms []v1beta1.SelectorTerm) []any {
+	out := make([]any, 0, len(terms))
+	for _, t := range terms {
+		term := map[string]any{}
+		if t.ID != "" {
+			term["id"] = t.ID
+		}
+		if len(t.Tags) > 0 {
+			term["tags"] = t.Tags
+		}
+		out = append(out, term)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEKSAutoMode(t *testing.T) {
+	type want struct {
+		label string
+		ref   string
+		err   string
+	}
+
+	cases := map[string]struct {
+		reason string
+		prov   nodeProvider
+		want   want
+	}{
+		"AWS": {
+			reason: "Auto Mode NodePools should use eks.amazonaws.com labels and the default NodeClass.",
+			prov:   &awsProvider{},
+			want:   want{label: "eks.amazonaws.com/instance-category", ref: "eks.amazonaws.com/NodeClass/default"},
+		},
+		"Azure": {
+			reason: "Auto Mode should require the AWS provider.",
+			prov:   &azureProvider{},
+			want:   want{err: `eks-auto-mode requires provider "aws"`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			prov, err := eksAutoMode(tc.prov)
+			if err != nil {
+				got.err = err.Error()
+			} else {
+				ref := prov.NodeClassRef()
+				got.label = prov.CategoryLabel()
+				got.ref = ref.Group + "/" + ref.Kind + "/" + ref.Name
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\neksAutoMode(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
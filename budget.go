@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/response"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// hoursPerMonth is the number of hours AWS uses to estimate monthly cost.
+const hoursPerMonth = 730
+
+// unitPrice is the approximate on-demand price, in US dollars per hour, of one
+// vCPU and of one GiB of memory in an instance family.
+type unitPrice struct {
+	CPU    float64
+	Memory float64
+
+	// MemoryPerCPU is the typical GiB of memory per vCPU of the family. Nodes
+	// come with both, so the budget buys vCPUs together with this much memory.
+	MemoryPerCPU float64
+}
+
+// categoryPricing approximates us-east-1 on-demand pricing for the instance
+// categories the Function may select.
+var categoryPricing = map[string]unitPrice{
+	"c": {CPU: 0.0340, Memory: 0.0043, MemoryPerCPU: 2},
+	"m": {CPU: 0.0316, Memory: 0.0041, MemoryPerCPU: 4},
+	"r": {CPU: 0.0316, Memory: 0.0040, MemoryPerCPU: 8},
+	"t": {CPU: 0.0208, Memory: 0.0052, MemoryPerCPU: 4},
+}
+
+// budgetFor returns the monthly spend ceiling that applies to an XR in the
+// supplied environment, or nil if the Input doesn't set one.
+func budgetFor(b *v1beta1.Budget, cxEnv string) *k8sresource.Quantity {
+	if b == nil {
+		return nil
+	}
+	if q, ok := b.Environments[cxEnv]; ok {
+		return &q
+	}
+	return b.MaxMonthlySpend
+}
+
+// budgetLimits returns the largest CPU and memory limits whose monthly cost
+// fits the supplied spend in every one of the supplied instance categories.
+// Categories without known pricing are priced like the m category. It returns
+// false if the spend doesn't limit them, because there are no categories to
+// price or the limits are too large to represent.
+func budgetLimits(spend *k8sresource.Quantity, categories []string) (cpu, memory k8sresource.Quantity, limited bool) {
+	if len(categories) == 0 {
+		return cpu, memory, false
+	}
+	dollars := spend.AsApproximateFloat64()
+
+	maxCPU, maxMemory := math.Inf(1), math.Inf(1)
+	for _, c := range categories {
+		p, ok := categoryPricing[c]
+		if !ok {
+			p = categoryPricing["m"]
+		}
+		vcpus := dollars / (hoursPerMonth * (p.CPU + p.MemoryPerCPU*p.Memory))
+		maxCPU = math.Min(maxCPU, vcpus)
+		maxMemory = math.Min(maxMemory, vcpus*p.MemoryPerCPU)
+	}
+
+	// Converting floats beyond the range of int64 is implementation defined.
+	milliCPU, memoryBytes := math.Floor(maxCPU*1000), math.Floor(maxMemory*1024)*1024*1024
+	if math.IsNaN(milliCPU) || math.IsNaN(memoryBytes) || milliCPU >= math.MaxInt64 || memoryBytes >= math.MaxInt64 {
+		return cpu, memory, false
+	}
+	cpu = *k8sresource.NewMilliQuantity(int64(milliCPU), k8sresource.DecimalSI)
+	memory = *k8sresource.NewQuantity(int64(memoryBytes), k8sresource.BinarySI)
+	return cpu, memory, true
+}
+
+// budgetClamp describes limits that were lowered to fit a budget.
+type budgetClamp struct {
+	Resource  string
+	Requested k8sresource.Quantity
+	Capped    k8sresource.Quantity
+}
+
+func (c budgetClamp) String() string {
+	return fmt.Sprintf("%s %s -> %s", c.Resource, c.Requested.String(), c.Capped.String())
+}
+
+// clampToBudget lowers any of the supplied limits that exceed what the spend
+// can buy in the supplied instance categories. It returns a description of
+// every limit it lowered.
+func clampToBudget(cpu, memory *k8sresource.Quantity, spend *k8sresource.Quantity, categories []string) []budgetClamp {
+	maxCPU, maxMemory, limited := budgetLimits(spend, categories)
+	if !limited {
+		return nil
+	}
+
+	var clamped []budgetClamp
+	if cpu.Cmp(maxCPU) > 0 {
+		clamped = append(clamped, budgetClamp{Resource: "cpu", Requested: cpu.DeepCopy(), Capped: maxCPU})
+		*cpu = maxCPU
+	}
+	if memory.Cmp(maxMemory) > 0 {
+		clamped = append(clamped, budgetClamp{Resource: "memory", Requested: memory.DeepCopy(), Capped: maxMemory})
+		*memory = maxMemory
+	}
+	return clamped
+}
+
+// fitBudget clamps the supplied limits so a fully scaled out NodePool of the
+// supplied instance categories fits the supplied monthly spend, and reports
+// whether it did with the BudgetCapped condition.
+func (f *Function) fitBudget(rsp *fnv1.RunFunctionResponse, spend *k8sresource.Quantity, prov nodeProvider, categories []string, cpu, memory *k8sresource.Quantity) {
+	pricing := make([]string, len(categories))
+	for i, c := range categories {
+		pricing[i] = prov.PricingCategory(c)
+	}
+	clamped := clampToBudget(cpu, memory, spend, pricing)
+	if len(clamped) == 0 {
+		response.ConditionFalse(rsp, "BudgetCapped", "WithinBudget").
+			WithMessage(fmt.Sprintf("NodePool limits fit the monthly budget of $%s", spend.String())).
+			TargetCompositeAndClaim()
+		return
+	}
+	changes := make([]string, len(clamped))
+	for i, c := range clamped {
+		changes[i] = c.String()
+	}
+	msg := fmt.Sprintf("NodePool limits exceed the monthly budget of $%s for instance categories %v, clamped %s",
+		spend.String(), categories, strings.Join(changes, ", "))
+	response.Warning(rsp, errors.New(msg)).TargetCompositeAndClaim()
+	response.ConditionTrue(rsp, "BudgetCapped", "LimitsClamped").
+		WithMessage(msg).
+		TargetCompositeAndClaim()
+	f.log.Info("Clamped NodePool limits to budget", "budget", spend.String(), "clamped", changes)
+}
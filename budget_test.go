@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestBudgetFor(t *testing.T) {
+	spend := k8sresource.MustParse("100")
+
+	cases := map[string]struct {
+		reason string
+		budget *v1beta1.Budget
+		cxEnv  string
+		want   string
+	}{
+		"NoBudget": {
+			reason: "No budget applies when the Input doesn't set one",
+			cxEnv:  "production",
+		},
+		"Default": {
+			reason: "MaxMonthlySpend applies to environments without an override",
+			budget: &v1beta1.Budget{
+				MaxMonthlySpend: &spend,
+				Environments:    map[string]k8sresource.Quantity{"production": k8sresource.MustParse("500")},
+			},
+			cxEnv: "development",
+			want:  "100",
+		},
+		"EnvironmentOverride": {
+			reason: "An environment override takes precedence over MaxMonthlySpend",
+			budget: &v1beta1.Budget{
+				MaxMonthlySpend: &spend,
+				Environments:    map[string]k8sresource.Quantity{"production": k8sresource.MustParse("500")},
+			},
+			cxEnv: "production",
+			want:  "500",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			if q := budgetFor(tc.budget, tc.cxEnv); q != nil {
+				got = q.String()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nbudgetFor(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestClampToBudget(t *testing.T) {
+	type want struct {
+		cpu     string
+		memory  string
+		clamped []string
+	}
+
+	cases := map[string]struct {
+		reason     string
+		cpu        string
+		memory     string
+		spend      string
+		categories []string
+		want       want
+	}{
+		"WithinBudget": {
+			reason:     "Limits that fit the budget should not be changed",
+			cpu:        "1",
+			memory:     "1Gi",
+			spend:      "1000",
+			categories: []string{"m"},
+			want: want{
+				cpu:    "1",
+				memory: "1Gi",
+			},
+		},
+		"ClampBoth": {
+			reason:     "Limits that exceed the budget should be lowered to the largest limits it can buy",
+			cpu:        "100",
+			memory:     "1000Gi",
+			spend:      "100",
+			categories: []string{"m"},
+			want: want{
+				cpu:     "2853m",
+				memory:  "11689Mi",
+				clamped: []string{"cpu 100 -> 2853m", "memory 1000Gi -> 11689Mi"},
+			},
+		},
+		"MostExpensiveCategoryWins": {
+			reason:     "The budget should be able to buy the limits in every selected category",
+			cpu:        "100",
+			memory:     "1000Gi",
+			spend:      "100",
+			categories: []string{"r", "c"},
+			want: want{
+				cpu:     "2153m",
+				memory:  "6585Mi",
+				clamped: []string{"cpu 100 -> 2153m", "memory 1000Gi -> 6585Mi"},
+			},
+		},
+		"UnknownCategory": {
+			reason:     "Categories without known pricing should be priced like the m category",
+			cpu:        "100",
+			memory:     "1000Gi",
+			spend:      "100",
+			categories: []string{"x"},
+			want: want{
+				cpu:     "2853m",
+				memory:  "11689Mi",
+				clamped: []string{"cpu 100 -> 2853m", "memory 1000Gi -> 11689Mi"},
+			},
+		},
+		"NoCategories": {
+			reason: "Limits should not be changed when there are no categories to price",
+			cpu:    "100",
+			memory: "1000Gi",
+			spend:  "100",
+			want: want{
+				cpu:    "100",
+				memory: "1000Gi",
+			},
+		},
+		"UnrepresentableLimits": {
+			reason:     "Limits should not be changed when the budget buys more than can be represented",
+			cpu:        "100",
+			memory:     "1000Gi",
+			spend:      "1e18",
+			categories: []string{"m"},
+			want: want{
+				cpu:    "100",
+				memory: "1000Gi",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cpu := k8sresource.MustParse(tc.cpu)
+			memory := k8sresource.MustParse(tc.memory)
+			spend := k8sresource.MustParse(tc.spend)
+
+			var clamped []string
+			for _, c := range clampToBudget(&cpu, &memory, &spend, tc.categories) {
+				clamped = append(clamped, c.String())
+			}
+
+			got := want{cpu: cpu.String(), memory: memory.String(), clamped: clamped}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nclampToBudget(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
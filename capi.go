@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	corev1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// autoscalerClusterAPI selects Cluster API machines, provisioned by the
+// Cluster API AWS provider (CAPA).
+const autoscalerClusterAPI = "cluster-api"
+
+// API versions of the Cluster API objects the Function composes. Only
+// cluster.x-k8s.io/v1beta2 MachineDeployments taint their machines' nodes.
+const (
+	machineDeploymentAPIVersion  = "cluster.x-k8s.io/v1beta2"
+	awsMachineTemplateAPIVersion = "infrastructure.cluster.x-k8s.io/v1beta2"
+)
+
+// API groups of the Cluster API templates a MachineDeployment references.
+const (
+	kubeadmConfigTemplateAPIGroup = "bootstrap.cluster.x-k8s.io"
+	awsMachineTemplateAPIGroup    = "infrastructure.cluster.x-k8s.io"
+)
+
+// machineTaintPropagationAlways keeps a Machine's taints on its Node, restoring
+// them if they're removed.
+const machineTaintPropagationAlways = "Always"
+
+// resourceNameAWSMachineTemplate is the name of the composed AWSMachineTemplate
+// of a MachineDeployment.
+const resourceNameAWSMachineTemplate = resource.Name("awsmachinetemplate")
+
+// defaultClusterAPINamespace is the namespace of Cluster API objects when the
+// Input doesn't say.
+const defaultClusterAPINamespace = "default"
+
+// labelClusterAPIClusterName is the label Cluster API uses to find the objects
+// of a workload cluster.
+const labelClusterAPIClusterName = "cluster.x-k8s.io/cluster-name"
+
+// machineShape returns the node shape of the first of the supplied categories
+// with an instance type offered in the region, narrowed to that instance type.
+// A MachineDeployment launches a single instance type. Categories without a
+// node shape use the m category's.
+func machineShape(categories, offered []string) (nodeShape, error) {
+	shapes := offeredShapes(categories, offered)
+	if len(shapes) == 0 {
+		return nodeShape{}, errors.Errorf("none of the instance types machines of categories %v launch are offered", categories)
+	}
+	s := shapes[0]
+	s.InstanceTypes = s.InstanceTypes[:1]
+	return s, nil
+}
+
+// machineTaints returns the supplied taints in Cluster API format.
+func machineTaints(taints []corev1.Taint) []any {
+	out := make([]any, len(taints))
+	for i, t := range taints {
+		mt := map[string]any{
+			"key":         t.Key,
+			"effect":      string(t.Effect),
+			"propagation": machineTaintPropagationAlways,
+		}
+		if t.Value != "" {
+			mt["value"] = t.Value
+		}
+		out[i] = mt
+	}
+	return out
+}
+
+// newMachineDeployment returns a Cluster API MachineDeployment, and the
+// AWSMachineTemplate of its machines, that launches as many offered instances
+// of the first of the supplied categories as fit the supplied limits. The
+// machines' nodes have the supplied taints, and their instances the supplied
+// tags.
+//
+// AWSMachineTemplates are immutable, so the template is named after the
+// MachineDeployment and a hash of its spec. Changing the spec composes a new
+// template, which rolls the MachineDeployment's machines.
+func newMachineDeployment(name, clusterName string, cfg *v1beta1.ClusterAPI, categories, offered []string, taints []corev1.Taint, tags map[string]string, cpu, memory k8sresource.Quantity) (*composed.Unstructured, *composed.Unstructured, error) {
+	if cfg == nil {
+		return nil, nil, errors.New("the Input's clusterAPI must be set to compose a MachineDeployment")
+	}
+	namespace := withDefault(cfg.Namespace, defaultClusterAPINamespace)
+	shape, err := machineShape(categories, offered)
+	if err != nil {
+		return nil, nil, err
+	}
+	replicas := nodeGroupMaxSize([]nodeShape{shape}, cpu, memory, 1)
+
+	machine := map[string]any{
+		"instanceType": shape.InstanceTypes[0],
+	}
+	if cfg.IAMInstanceProfile != "" {
+		machine["iamInstanceProfile"] = cfg.IAMInstanceProfile
+	}
+	if cfg.SSHKeyName != "" {
+		machine["sshKeyName"] = cfg.SSHKeyName
+	}
+	if len(tags) > 0 {
+		additional := make(map[string]any, len(tags))
+		for k, v := range tags {
+			additional[k] = v
+		}
+		machine["additionalTags"] = additional
+	}
+	b, err := json.Marshal(machine)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot marshal AWSMachineTemplate spec")
+	}
+	sum := sha256.Sum256(b)
+	templateName := truncateName(name + "-" + hex.EncodeToString(sum[:])[:nameHashLength])
+
+	tmpl := composed.New()
+	tmpl.SetAPIVersion(awsMachineTemplateAPIVersion)
+	tmpl.SetKind("AWSMachineTemplate")
+	tmpl.SetNamespace(namespace)
+	tmpl.SetName(templateName)
+	if err := tmpl.SetValue("spec.template.spec", machine); err != nil {
+		return nil, nil, errors.Wrap(err, "cannot set spec.template.spec of AWSMachineTemplate")
+	}
+
+	md := composed.New()
+	md.SetAPIVersion(machineDeploymentAPIVersion)
+	md.SetKind("MachineDeployment")
+	md.SetNamespace(namespace)
+	md.SetName(name)
+	md.SetLabels(map[string]string{labelClusterAPIClusterName: clusterName})
+	machineSpec := map[string]any{
+		"clusterName": clusterName,
+		"version":     cfg.Version,
+		"bootstrap": map[string]any{
+			"configRef": map[string]any{
+				"apiGroup": kubeadmConfigTemplateAPIGroup,
+				"kind":     "KubeadmConfigTemplate",
+				"name":     cfg.BootstrapConfigTemplate,
+			},
+		},
+		"infrastructureRef": map[string]any{
+			"apiGroup": awsMachineTemplateAPIGroup,
+			"kind":     "AWSMachineTemplate",
+			"name":     templateName,
+		},
+	}
+	if len(taints) > 0 {
+		machineSpec["taints"] = machineTaints(taints)
+	}
+	spec := map[string]any{
+		"clusterName": clusterName,
+		"replicas":    replicas,
+		"template":    map[string]any{"spec": machineSpec},
+	}
+	if err := md.SetValue("spec", spec); err != nil {
+		return nil, nil, errors.Wrap(err, "cannot set spec of MachineDeployment")
+	}
+	return md, tmpl, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestNewMachineDeployment(t *testing.T) {
+	cfg := &v1beta1.ClusterAPI{Version: "v1.30.2", BootstrapConfigTemplate: "workers"}
+	offered := []string{"c5.xlarge", "m5.large", "m6i.xlarge", "r6i.xlarge"}
+
+	type want struct {
+		instanceType string
+		replicas     any
+		templateRef  any
+		taints       any
+		tags         any
+		err          string
+	}
+
+	cases := map[string]struct {
+		reason     string
+		cfg        *v1beta1.ClusterAPI
+		categories []string
+		taints     []corev1.Taint
+		tags       map[string]string
+		cpu        string
+		memory     string
+		want       want
+	}{
+		"FirstCategory": {
+			reason:     "Machines should use the first category's instance type, and as many replicas as fit the limits.",
+			cfg:        cfg,
+			categories: []string{"r", "m"},
+			cpu:        "64",
+			memory:     "256Gi",
+			want:       want{instanceType: "r6i.xlarge", replicas: int64(8), templateRef: "np1-7825784e"},
+		},
+		"OfferedInstanceType": {
+			reason:     "Machines should use the category's first offered instance type.",
+			cfg:        cfg,
+			categories: []string{"c"},
+			cpu:        "8",
+			memory:     "16Gi",
+			want:       want{instanceType: "c5.xlarge", replicas: int64(2), templateRef: "np1-2423f8c4"},
+		},
+		"FirstOfferedCategory": {
+			reason:     "Machines should use the first category with an offered instance type.",
+			cfg:        cfg,
+			categories: []string{"t", "m"},
+			cpu:        "8",
+			memory:     "64Gi",
+			want:       want{instanceType: "m6i.xlarge", replicas: int64(2), templateRef: "np1-6797f780"},
+		},
+		"UnknownCategory": {
+			reason:     "Categories without a node shape should fall back to the m category's.",
+			cfg:        cfg,
+			categories: []string{"x"},
+			cpu:        "8",
+			memory:     "64Gi",
+			want:       want{instanceType: "m6i.xlarge", replicas: int64(2), templateRef: "np1-6797f780"},
+		},
+		"NoneOffered": {
+			reason:     "An error should be returned if no instance type of the categories is offered.",
+			cfg:        cfg,
+			categories: []string{"t"},
+			want:       want{err: "none of the instance types machines of categories [t] launch are offered"},
+		},
+		"Taints": {
+			reason:     "Machines' nodes should have the supplied taints, kept by Cluster API.",
+			cfg:        cfg,
+			categories: []string{"m"},
+			taints: []corev1.Taint{
+				{Key: "tier", Value: "production", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Effect: corev1.TaintEffectNoExecute},
+			},
+			want: want{
+				instanceType: "m6i.xlarge",
+				replicas:     int64(1),
+				templateRef:  "np1-6797f780",
+				taints: []any{
+					map[string]any{"key": "tier", "value": "production", "effect": "NoSchedule", "propagation": "Always"},
+					map[string]any{"key": "dedicated", "effect": "NoExecute", "propagation": "Always"},
+				},
+			},
+		},
+		"Tags": {
+			reason:     "Machines' instances should have the supplied tags, which are part of the AWSMachineTemplate's hash.",
+			cfg:        cfg,
+			categories: []string{"m"},
+			tags:       map[string]string{labelTeam: "checkout"},
+			want: want{
+				instanceType: "m6i.xlarge",
+				replicas:     int64(1),
+				templateRef:  "np1-225ca089",
+				tags:         map[string]any{labelTeam: "checkout"},
+			},
+		},
+		"NoConfig": {
+			reason: "An error should be returned if the Input doesn't configure Cluster API.",
+			want:   want{err: "the Input's clusterAPI must be set to compose a MachineDeployment"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cpu, memory := k8sresource.MustParse("1"), k8sresource.MustParse("1Gi")
+			if tc.cpu != "" {
+				cpu, memory = k8sresource.MustParse(tc.cpu), k8sresource.MustParse(tc.memory)
+			}
+			md, tmpl, err := newMachineDeployment("np1", "cluster", tc.cfg, tc.categories, offered, tc.taints, tc.tags, cpu, memory)
+			got := want{}
+			if err != nil {
+				got.err = err.Error()
+			} else {
+				got.instanceType, _ = tmpl.GetString("spec.template.spec.instanceType")
+				got.replicas, _ = md.GetValue("spec.replicas")
+				got.templateRef, _ = md.GetValue("spec.template.spec.infrastructureRef.name")
+				got.taints, _ = md.GetValue("spec.template.spec.taints")
+				got.tags, _ = tmpl.GetValue("spec.template.spec.additionalTags")
+				if tmpl.GetName() != got.templateRef {
+					t.Errorf("\n%s\nnewMachineDeployment(...): references AWSMachineTemplate %q, want %q", tc.reason, got.templateRef, tmpl.GetName())
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nnewMachineDeployment(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/function-sdk-go/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// A catalog is an offline record of the instance types AWS offers, and the VM
+// SKUs Azure offers. It lets the Function run without calling the EC2 API, and
+// is the only source of Azure SKU availability.
+type catalog struct {
+	// Regions maps an AWS region to what is offered in it.
+	Regions map[string]catalogRegion `json:"regions,omitempty"`
+
+	// Locations maps an Azure location to what is offered in it.
+	Locations map[string]catalogLocation `json:"locations,omitempty"`
+
+	// InstanceTypes describes AWS instance types, by name.
+	InstanceTypes map[string]catalogInstanceType `json:"instanceTypes,omitempty"`
+}
+
+// catalogInstanceType describes an AWS instance type.
+type catalogInstanceType struct {
+	VCPUs                     int32 `json:"vcpus"`
+	MemoryMiB                 int64 `json:"memoryMiB"`
+	GPUs                      int32 `json:"gpus,omitempty"`
+	MaxNetworkInterfaces      int32 `json:"maxNetworkInterfaces,omitempty"`
+	IPv4AddressesPerInterface int32 `json:"ipv4AddressesPerInterface,omitempty"`
+}
+
+// catalogRegion is what AWS offers in a region.
+type catalogRegion struct {
+	// InstanceTypes offered in the region.
+	InstanceTypes []string `json:"instanceTypes"`
+
+	// CapacityReservations in the region.
+	CapacityReservations []catalogCapacityReservation `json:"capacityReservations,omitempty"`
+}
+
+// catalogCapacityReservation is an On-Demand Capacity Reservation.
+type catalogCapacityReservation struct {
+	ID                 string            `json:"id"`
+	OwnerID            string            `json:"ownerID,omitempty"`
+	InstanceType       string            `json:"instanceType"`
+	AvailabilityZone   string            `json:"availabilityZone"`
+	TotalInstances     int32             `json:"totalInstances"`
+	AvailableInstances int32             `json:"availableInstances"`
+	State              string            `json:"state,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+}
+
+// catalogLocation is what Azure offers in a location.
+type catalogLocation struct {
+	// SKUs of the VMs available in the location, e.g. Standard_D4s_v5.
+	SKUs []string `json:"skus"`
+}
+
+// loadCatalog loads a catalog from a YAML or JSON file.
+func loadCatalog(path string) (*catalog, error) {
+	b, err := os.ReadFile(path) //nolint:gosec // Reading a user supplied file is intended.
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read offerings catalog %q", path)
+	}
+	c := &catalog{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse offerings catalog %q", path)
+	}
+	return c, nil
+}
+
+// instanceCategory returns the Karpenter instance category of the supplied
+// instance type, e.g. c for c8g.16xlarge.
+func instanceCategory(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	if i := strings.IndexFunc(family, unicode.IsDigit); i >= 0 {
+		return family[:i]
+	}
+	return family
+}
+
+// offeredCategories returns the instance categories the catalog offers in the
+// supplied region of the supplied provider, and whether it knows the region.
+func (c *catalog) offeredCategories(provider, region string) (map[string]bool, bool) {
+	offered := map[string]bool{}
+	if provider == providerAzure {
+		l, ok := c.Locations[region]
+		for _, sku := range l.SKUs {
+			offered[skuFamily(sku)] = true
+		}
+		return offered, ok
+	}
+	r, ok := c.Regions[region]
+	for _, t := range r.InstanceTypes {
+		offered[instanceCategory(t)] = true
+	}
+	return offered, ok
+}
+
+// client returns an EC2 client that answers from the catalog for the supplied
+// region. It satisfies the Function's ec2 field.
+func (c *catalog) client(_ context.Context, region string) (ec2API, error) {
+	return catalogEC2{region: region, offered: c.Regions[region], types: c.InstanceTypes}, nil
+}
+
+// catalogEC2 is an EC2 client backed by a catalog.
+type catalogEC2 struct {
+	region  string
+	offered catalogRegion
+	types   map[string]catalogInstanceType
+}
+
+// DescribeInstanceTypeOfferings returns every instance type the catalog offers
+// in the client's region. It ignores the supplied filters.
+func (c catalogEC2) DescribeInstanceTypeOfferings(_ context.Context, _ *ec2.DescribeInstanceTypeOfferingsInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	out := &ec2.DescribeInstanceTypeOfferingsOutput{
+		InstanceTypeOfferings: make([]types.InstanceTypeOffering, 0, len(c.offered.InstanceTypes)),
+	}
+	for _, t := range c.offered.InstanceTypes {
+		out.InstanceTypeOfferings = append(out.InstanceTypeOfferings, types.InstanceTypeOffering{
+			InstanceType: types.InstanceType(t),
+			Location:     &c.region,
+			LocationType: types.LocationTypeRegion,
+		})
+	}
+	return out, nil
+}
+
+// DescribeCapacityReservations returns every capacity reservation the catalog
+// records in the client's region. Reservations without a state are active.
+// It ignores the supplied filters.
+func (c catalogEC2) DescribeCapacityReservations(_ context.Context, _ *ec2.DescribeCapacityReservationsInput, _ ...func(*ec2.Options)) (*ec2.DescribeCapacityReservationsOutput, error) {
+	out := &ec2.DescribeCapacityReservationsOutput{
+		CapacityReservations: make([]types.CapacityReservation, 0, len(c.offered.CapacityReservations)),
+	}
+	for _, r := range c.offered.CapacityReservations {
+		cr := types.CapacityReservation{
+			CapacityReservationId:  aws.String(r.ID),
+			OwnerId:                aws.String(r.OwnerID),
+			InstanceType:           aws.String(r.InstanceType),
+			AvailabilityZone:       aws.String(r.AvailabilityZone),
+			TotalInstanceCount:     aws.Int32(r.TotalInstances),
+			AvailableInstanceCount: aws.Int32(r.AvailableInstances),
+			State:                  types.CapacityReservationState(withDefault(r.State, string(types.CapacityReservationStateActive))),
+		}
+		for k, v := range r.Tags {
+			cr.Tags = append(cr.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		out.CapacityReservations = append(out.CapacityReservations, cr)
+	}
+	return out, nil
+}
+
+// DescribeInstanceTypes describes the supplied instance types the catalog
+// describes that are offered in the client's region, or all of them if none
+// are supplied. It ignores the supplied filters.
+func (c catalogEC2) DescribeInstanceTypes(_ context.Context, params *ec2.DescribeInstanceTypesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	out := &ec2.DescribeInstanceTypesOutput{}
+	for _, name := range c.offered.InstanceTypes {
+		t, ok := c.types[name]
+		if !ok {
+			continue
+		}
+		if len(params.InstanceTypes) > 0 && !slices.Contains(params.InstanceTypes, types.InstanceType(name)) {
+			continue
+		}
+		info := types.InstanceTypeInfo{
+			InstanceType: types.InstanceType(name),
+			VCpuInfo:     &types.VCpuInfo{DefaultVCpus: aws.Int32(t.VCPUs)},
+			MemoryInfo:   &types.MemoryInfo{SizeInMiB: aws.Int64(t.MemoryMiB)},
+			NetworkInfo: &types.NetworkInfo{
+				MaximumNetworkInterfaces:  aws.Int32(t.MaxNetworkInterfaces),
+				Ipv4AddressesPerInterface: aws.Int32(t.IPv4AddressesPerInterface),
+			},
+		}
+		if t.GPUs > 0 {
+			info.GpuInfo = &types.GpuInfo{Gpus: []types.GpuDeviceInfo{{Count: aws.Int32(t.GPUs)}}}
+		}
+		out.InstanceTypes = append(out.InstanceTypes, info)
+	}
+	return out, nil
+}
+
+// DescribeRegions returns an error. A catalog records only the regions it has
+// offerings for, so it can't tell whether a region exists.
+func (c catalogEC2) DescribeRegions(_ context.Context, _ *ec2.DescribeRegionsInput, _ ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	return nil, errors.New("the offerings catalog doesn't describe regions")
+}
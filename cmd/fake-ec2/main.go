@@ -0,0 +1,37 @@
+// Package main serves a fake EC2 API from fixture data, for running the
+// Function against without AWS.
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/crossplane/function-nodepools/internal/fakeec2"
+)
+
+// CLI of the fake EC2 API.
+type CLI struct {
+	Fixture string `arg:"" help:"YAML or JSON file containing the regions and instance types to serve." type:"existingfile"`
+	Address string `help:"Address at which to listen for EC2 API requests." default:"127.0.0.1:4566"`
+}
+
+// Run the fake EC2 API.
+func (c *CLI) Run() error {
+	f, err := fakeec2.LoadFixture(c.Fixture)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              c.Address,
+		Handler:           fakeec2.New(f),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+func main() {
+	ctx := kong.Parse(&CLI{}, kong.Description("A fake EC2 API for testing the Function."))
+	ctx.FatalIfErrorf(ctx.Run())
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	fncontext "github.com/crossplane/function-sdk-go/context"
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/request"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/crossplane/function-sdk-go/response"
+	"google.golang.org/protobuf/types/known/structpb"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// Defaults of the Input's context.
+const (
+	defaultContextSummaryKey          = "nodepools.fn.crossplane.io/summary"
+	defaultContextRegion              = "region"
+	defaultContextClusterName         = "clusterName"
+	defaultContextKarpenterAPIVersion = "karpenterAPIVersion"
+	defaultContextNodeRoleARN         = "nodeRoleArn"
+)
+
+// clusterContext is cluster metadata read from the pipeline Context. Fields
+// are empty if the Context doesn't hold them.
+type clusterContext struct {
+	Region              string
+	ClusterName         string
+	KarpenterAPIVersion string
+	NodeRoleARN         string
+}
+
+// readClusterContext reads cluster metadata from the pipeline Context, as
+// configured by the supplied Input context. It returns empty metadata if c is
+// nil or the Context doesn't hold the configured key.
+func readClusterContext(req *fnv1.RunFunctionRequest, c *v1beta1.Context) (clusterContext, error) {
+	out := clusterContext{}
+	if c == nil {
+		return out, nil
+	}
+	key := withDefault(c.Key, fncontext.KeyEnvironment)
+	v, ok := request.GetContextKey(req, key)
+	if !ok {
+		return out, nil
+	}
+	m, ok := v.AsInterface().(map[string]any)
+	if !ok {
+		return out, errors.Errorf("Context key %q must hold an object", key)
+	}
+	p := fieldpath.Pave(m)
+
+	fields := []struct {
+		path string
+		into *string
+	}{
+		{withDefault(c.Fields.Region, defaultContextRegion), &out.Region},
+		{withDefault(c.Fields.ClusterName, defaultContextClusterName), &out.ClusterName},
+		{withDefault(c.Fields.KarpenterAPIVersion, defaultContextKarpenterAPIVersion), &out.KarpenterAPIVersion},
+		{withDefault(c.Fields.NodeRoleARN, defaultContextNodeRoleARN), &out.NodeRoleARN},
+	}
+	for _, f := range fields {
+		s, err := p.GetString(f.path)
+		if fieldpath.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return out, errors.Wrapf(err, "cannot read %s of Context key %q", f.path, key)
+		}
+		*f.into = s
+	}
+	return out, nil
+}
+
+// nodePoolSummary returns a summary of the supplied NodePool, or NodeGroup or
+// MachineDeployment, that downstream functions read from the pipeline Context.
+func nodePoolSummary(nodePool *composed.Unstructured, provider, region, autoscaler string, categories []string, cpu, memory k8sresource.Quantity) map[string]any {
+	cs := make([]any, len(categories))
+	for i, c := range categories {
+		cs[i] = c
+	}
+	return map[string]any{
+		"apiVersion": nodePool.GetAPIVersion(),
+		"kind":       nodePool.GetKind(),
+		"name":       nodePool.GetName(),
+		"provider":   provider,
+		"region":     region,
+		"autoscaler": autoscaler,
+		"categories": cs,
+		"limits": map[string]any{
+			"cpu":    cpu.String(),
+			"memory": memory.String(),
+		},
+	}
+}
+
+// writeSummary writes the supplied summary of the NodePool, or NodeGroup, the
+// Function decided on to the pipeline Context, as configured by the supplied
+// Input context. It does nothing if c is nil.
+func writeSummary(rsp *fnv1.RunFunctionResponse, c *v1beta1.Context, summary map[string]any) error {
+	if c == nil {
+		return nil
+	}
+	v, err := structpb.NewValue(summary)
+	if err != nil {
+		return errors.Wrapf(err, "cannot convert summary to %T", v)
+	}
+	response.SetContextKey(rsp, withDefault(c.SummaryKey, defaultContextSummaryKey), v)
+	return nil
+}
+
+// clusterNameOf returns the name of the supplied XR's cluster, from the
+// supplied cluster metadata or else the XR's spec.ClusterName.
+func clusterNameOf(cc clusterContext, xr *resource.Composite) (string, error) {
+	if cc.ClusterName != "" {
+		return cc.ClusterName, nil
+	}
+	name, err := xr.Resource.GetString("spec.ClusterName")
+	return name, errors.Wrapf(err, "cannot read spec.ClusterName field of %s", xr.Resource.GetKind())
+}
+
+// withDefault returns s, or def if s is empty.
+func withDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRunFunctionContext(t *testing.T) {
+	type want struct {
+		nodePool map[string]any
+		summary  map[string]any
+		fatal    string
+	}
+
+	cases := map[string]struct {
+		reason  string
+		input   string
+		context map[string]any
+		want    want
+	}{
+		"ContextTakesPrecedence": {
+			reason: "Cluster metadata in the Context should take precedence over the XR, and the Function should write a summary to the Context.",
+			input:  `{"context": {}}`,
+			context: map[string]any{
+				"apiextensions.crossplane.io/environment": map[string]any{
+					"region":              "us-east-1",
+					"karpenterAPIVersion": "v1beta1",
+				},
+			},
+			want: want{
+				nodePool: map[string]any{
+					"apiVersion": "karpenter.sh/v1beta1",
+					"categories": []any{"m", "c"},
+				},
+				summary: map[string]any{
+					"apiVersion": "karpenter.sh/v1beta1",
+					"kind":       "NodePool",
+					"name":       "np1",
+					"provider":   "aws",
+					"region":     "us-east-1",
+					"autoscaler": "karpenter",
+					"categories": []any{"m", "c"},
+					"limits":     map[string]any{"cpu": "1", "memory": "1000Mi"},
+				},
+			},
+		},
+		"ConfiguredKeys": {
+			reason: "The Function should read the configured fields of the configured Context key, and write the summary to the configured key.",
+			input:  `{"context": {"key": "example.org/cluster", "fields": {"region": "aws.region"}, "summaryKey": "example.org/nodepool"}}`,
+			context: map[string]any{
+				"example.org/cluster": map[string]any{
+					"aws": map[string]any{"region": "us-east-1"},
+				},
+			},
+			want: want{
+				nodePool: map[string]any{
+					"apiVersion": "karpenter.sh/v1",
+					"categories": []any{"m", "c"},
+				},
+				summary: map[string]any{
+					"apiVersion": "karpenter.sh/v1",
+					"kind":       "NodePool",
+					"name":       "np1",
+					"provider":   "aws",
+					"region":     "us-east-1",
+					"autoscaler": "karpenter",
+					"categories": []any{"m", "c"},
+					"limits":     map[string]any{"cpu": "1", "memory": "1000Mi"},
+				},
+			},
+		},
+		"FallBackToXR": {
+			reason: "The Function should fall back to the XR's fields when the Context doesn't hold cluster metadata.",
+			input:  `{"context": {}}`,
+			want: want{
+				nodePool: map[string]any{
+					"apiVersion": "karpenter.sh/v1",
+					"categories": []any{"m"},
+				},
+				summary: map[string]any{
+					"apiVersion": "karpenter.sh/v1",
+					"kind":       "NodePool",
+					"name":       "np1",
+					"provider":   "aws",
+					"region":     "af-south-1",
+					"autoscaler": "karpenter",
+					"categories": []any{"m"},
+					"limits":     map[string]any{"cpu": "1", "memory": "1000Mi"},
+				},
+			},
+		},
+		"InvalidContextRegion": {
+			reason: "The Function should return a fatal result naming the Context when its region is invalid.",
+			input:  `{"context": {}}`,
+			context: map[string]any{
+				"apiextensions.crossplane.io/environment": map[string]any{"region": "us-est-1"},
+			},
+			want: want{
+				fatal: `invalid Context region of XNodePool "np1": unknown AWS region "us-est-1", did you mean "us-east-1"?`,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			in := resource.MustStructJSON(`{"apiVersion": "template.fn.crossplane.io/v1beta1", "kind": "Input", "example": "Hello, world"}`)
+			extra := resource.MustStructJSON(tc.input)
+			for k, v := range extra.GetFields() {
+				in.Fields[k] = v
+			}
+			ctx, err := structpb.NewStruct(tc.context)
+			if err != nil {
+				t.Fatalf("structpb.NewStruct(...): %v", err)
+			}
+			req := &fnv1.RunFunctionRequest{
+				Input:   in,
+				Context: ctx,
+				Observed: &fnv1.State{
+					Composite: &fnv1.Resource{
+						Resource: resource.MustStructJSON(`{
+							"apiVersion": "example.crossplane.io/v1alpha1",
+							"kind": "XNodePool",
+							"metadata": {"name": "np1"},
+							"spec": {"CxEnv": "development", "AwsRegion": "af-south-1"}
+						}`),
+					},
+				},
+			}
+
+			f := &Function{log: logging.NewNopLogger(), ec2: offerings.client}
+			rsp, err := f.RunFunction(context.Background(), req)
+			if err != nil {
+				t.Fatalf("\n%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+
+			fatal := ""
+			for _, r := range rsp.GetResults() {
+				if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+					fatal = r.GetMessage()
+				}
+			}
+			if diff := cmp.Diff(tc.want.fatal, fatal); diff != "" {
+				t.Fatalf("\n%s\nf.RunFunction(...): -want fatal, +got fatal:\n%s", tc.reason, diff)
+			}
+			if tc.want.fatal != "" {
+				return
+			}
+
+			np := rsp.GetDesired().GetResources()["nodepool"].GetResource().AsMap()
+			reqs := np["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["requirements"].([]any)
+			gotNodePool := map[string]any{
+				"apiVersion": np["apiVersion"],
+				"categories": reqs[0].(map[string]any)["values"],
+			}
+			if diff := cmp.Diff(tc.want.nodePool, gotNodePool); diff != "" {
+				t.Errorf("\n%s\nf.RunFunction(...): -want NodePool, +got NodePool:\n%s", tc.reason, diff)
+			}
+
+			key := "nodepools.fn.crossplane.io/summary"
+			if v := extra.GetFields()["context"].GetStructValue().GetFields()["summaryKey"].GetStringValue(); v != "" {
+				key = v
+			}
+			if diff := cmp.Diff(tc.want.summary, rsp.GetContext().GetFields()[key].GetStructValue().AsMap()); diff != "" {
+				t.Errorf("\n%s\nf.RunFunction(...): -want summary, +got summary:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
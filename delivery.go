@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// API version and kind of the provider-kubernetes Objects the Function wraps
+// NodePools in.
+const (
+	objectAPIVersion = "kubernetes.crossplane.io/v1alpha2"
+	objectKind       = "Object"
+)
+
+// defaultDeliveryProviderConfigField is the XR field the Function reads the
+// provider-kubernetes ProviderConfig name from when the Input doesn't say.
+const defaultDeliveryProviderConfigField = "spec.ProviderConfigName"
+
+// providerConfigName returns the name of the provider-kubernetes
+// ProviderConfig of the supplied XR's workload cluster.
+func providerConfigName(d *v1beta1.Delivery, xr *resource.Composite) (string, error) {
+	path := withDefault(d.ProviderConfigField, defaultDeliveryProviderConfigField)
+	name, err := xr.Resource.GetString(path)
+	if err != nil && !fieldpath.IsNotFound(err) {
+		return "", errors.Wrapf(err, "cannot read %s field of %s", path, xr.Resource.GetKind())
+	}
+	if name == "" {
+		name = d.ProviderConfigName
+	}
+	if name == "" {
+		return "", errors.Errorf("%s field of %s is not set, and the Input's delivery has no default providerConfigName", path, xr.Resource.GetKind())
+	}
+	return name, nil
+}
+
+// wrapInObject returns a provider-kubernetes Object with the supplied name
+// that creates the supplied resource in the cluster the supplied
+// ProviderConfig connects to. The Object is labelled like the resource. Its
+// status isn't part of the manifest.
+func wrapInObject(name string, res *composed.Unstructured, providerConfig string) (*composed.Unstructured, error) {
+	manifest := runtime.DeepCopyJSON(res.Object)
+	delete(manifest, "status")
+
+	o := composed.New()
+	o.SetAPIVersion(objectAPIVersion)
+	o.SetKind(objectKind)
+	o.SetName(name)
+	o.SetLabels(res.GetLabels())
+	if err := o.SetValue("spec.forProvider.manifest", manifest); err != nil {
+		return nil, errors.Wrapf(err, "cannot set spec.forProvider.manifest of %s", objectKind)
+	}
+	if err := o.SetValue("spec.providerConfigRef.name", providerConfig); err != nil {
+		return nil, errors.Wrapf(err, "cannot set spec.providerConfigRef.name of %s", objectKind)
+	}
+	return o, nil
+}
+
+// deliver replaces the supplied desired NodePool, composed as the supplied
+// resource name, and its supporting resources with provider-kubernetes Objects
+// that create them in the supplied XR's workload cluster. The NodePool's
+// Object is ready once the supplied observed NodePool is.
+func deliver(desired map[resource.Name]*resource.DesiredComposed, d *v1beta1.Delivery, xr *resource.Composite, name resource.Name, observed map[string]any, supporting map[resource.Name]*composed.Unstructured) error {
+	nodePool := desired[name].Resource
+	providerConfig, err := providerConfigName(d, xr)
+	if err != nil {
+		return errors.Wrapf(err, "cannot deliver %s for %s %q", nodePool.GetKind(), xr.Resource.GetKind(), xr.Resource.GetName())
+	}
+	object, err := wrapInObject(nodePool.GetName(), nodePool, providerConfig)
+	if err != nil {
+		return err
+	}
+	desired[name] = &resource.DesiredComposed{Resource: object, Ready: nodePoolReady(observed)}
+	for n, res := range supporting {
+		if object, err = wrapInObject(truncateName(res.GetName()+"-"+strings.ToLower(res.GetKind())), res, providerConfig); err != nil {
+			return err
+		}
+		desired[n] = &resource.DesiredComposed{Resource: object}
+	}
+	return nil
+}
+
+// objectManifest returns the resource the supplied desired Object creates, or
+// the supplied resource itself if it isn't an Object.
+func objectManifest(res map[string]any) map[string]any {
+	if res["apiVersion"] != objectAPIVersion || res["kind"] != objectKind {
+		return res
+	}
+	manifest, _, _ := unstructured.NestedMap(res, "spec", "forProvider", "manifest")
+	return manifest
+}
+
+// unwrapObject returns the resource the supplied observed Object last observed
+// in its cluster, or nil if it hasn't observed one yet.
+func unwrapObject(observed map[string]any) map[string]any {
+	if observed == nil {
+		return nil
+	}
+	manifest, _, _ := unstructured.NestedMap(observed, "status", "atProvider", "manifest")
+	return manifest
+}
+
+// nodePoolReady returns whether the supplied observed NodePool is ready, per
+// its Ready condition. karpenter.sh/v1beta1 NodePools have no conditions, so
+// they're ready once they exist.
+func nodePoolReady(np map[string]any) resource.Ready {
+	if np == nil {
+		return resource.ReadyFalse
+	}
+	conditions, found, _ := unstructured.NestedSlice(np, "status", "conditions")
+	if !found {
+		if apiVersion, _ := np["apiVersion"].(string); apiVersion == karpenterV1Beta1APIVersion {
+			return resource.ReadyTrue
+		}
+		return resource.ReadyFalse
+	}
+	for _, c := range conditions {
+		m, ok := c.(map[string]any)
+		if !ok || m["type"] != "Ready" {
+			continue
+		}
+		if m["status"] == "True" {
+			return resource.ReadyTrue
+		}
+		return resource.ReadyFalse
+	}
+	return resource.ReadyFalse
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestProviderConfigName(t *testing.T) {
+	type want struct {
+		name string
+		err  string
+	}
+
+	cases := map[string]struct {
+		reason string
+		d      *v1beta1.Delivery
+		spec   map[string]any
+		want   want
+	}{
+		"FromXR": {
+			reason: "The ProviderConfig named by the XR should be used.",
+			d:      &v1beta1.Delivery{ProviderConfigName: "default"},
+			spec:   map[string]any{"ProviderConfigName": "cluster-1"},
+			want:   want{name: "cluster-1"},
+		},
+		"CustomField": {
+			reason: "The ProviderConfig should be read from the configured field.",
+			d:      &v1beta1.Delivery{ProviderConfigField: "spec.cluster.providerConfig"},
+			spec:   map[string]any{"cluster": map[string]any{"providerConfig": "cluster-2"}},
+			want:   want{name: "cluster-2"},
+		},
+		"Default": {
+			reason: "The Input's ProviderConfig should be used if the XR doesn't name one.",
+			d:      &v1beta1.Delivery{ProviderConfigName: "default"},
+			want:   want{name: "default"},
+		},
+		"Unset": {
+			reason: "An error should be returned if neither the XR nor the Input name a ProviderConfig.",
+			d:      &v1beta1.Delivery{},
+			want:   want{err: "spec.ProviderConfigName field of XNodePool is not set, and the Input's delivery has no default providerConfigName"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xr := &resource.Composite{Resource: composite.New()}
+			xr.Resource.SetKind("XNodePool")
+			xr.Resource.Object["spec"] = tc.spec
+
+			n, err := providerConfigName(tc.d, xr)
+			got := want{name: n}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nproviderConfigName(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNodePoolReady(t *testing.T) {
+	object := func(manifest map[string]any) map[string]any {
+		return map[string]any{"status": map[string]any{"atProvider": map[string]any{"manifest": manifest}}}
+	}
+	withConditions := func(conditions ...any) map[string]any {
+		return map[string]any{"apiVersion": "karpenter.sh/v1", "status": map[string]any{"conditions": conditions}}
+	}
+
+	cases := map[string]struct {
+		reason   string
+		observed map[string]any
+		want     resource.Ready
+	}{
+		"NotObserved": {
+			reason: "A NodePool whose Object isn't observed shouldn't be ready.",
+			want:   resource.ReadyFalse,
+		},
+		"NotDelivered": {
+			reason:   "A NodePool the Object hasn't observed in its cluster yet shouldn't be ready.",
+			observed: map[string]any{"status": map[string]any{}},
+			want:     resource.ReadyFalse,
+		},
+		"Ready": {
+			reason:   "A NodePool with a true Ready condition should be ready.",
+			observed: object(withConditions(map[string]any{"type": "NodeClassReady", "status": "True"}, map[string]any{"type": "Ready", "status": "True"})),
+			want:     resource.ReadyTrue,
+		},
+		"NotReady": {
+			reason:   "A NodePool with a false Ready condition shouldn't be ready.",
+			observed: object(withConditions(map[string]any{"type": "Ready", "status": "False"})),
+			want:     resource.ReadyFalse,
+		},
+		"NoConditions": {
+			reason:   "A karpenter.sh/v1 NodePool without conditions hasn't been reconciled, and shouldn't be ready.",
+			observed: object(map[string]any{"apiVersion": "karpenter.sh/v1"}),
+			want:     resource.ReadyFalse,
+		},
+		"V1Beta1": {
+			reason:   "A karpenter.sh/v1beta1 NodePool should be ready once it exists.",
+			observed: object(map[string]any{"apiVersion": karpenterV1Beta1APIVersion}),
+			want:     resource.ReadyTrue,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := nodePoolReady(unwrapObject(tc.observed))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nnodePoolReady(unwrapObject(...)): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
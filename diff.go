@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/request"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/response"
+)
+
+// fieldChange is a change to a single field of a resource. Old is nil when the
+// field is being added, and New is nil when it is being removed.
+type fieldChange struct {
+	Path string
+	Old  any
+	New  any
+}
+
+// Drifts returns true if the change causes Karpenter to replace the existing
+// nodes of a NodePool.
+func (c fieldChange) Drifts() bool {
+	return c.Path == "spec.template" || strings.HasPrefix(c.Path, "spec.template.")
+}
+
+func (c fieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, jsonValue(c.Old), jsonValue(c.New))
+}
+
+// jsonValue formats a field value as compact JSON, or <none> if it is unset.
+func jsonValue(v any) string {
+	if v == nil {
+		return "<none>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// diffFields returns the changes needed to turn the observed object into the
+// desired object, ordered by path. Only fields the desired object sets are
+// compared, so fields defaulted by the API server aren't reported. Status is
+// ignored, because it's written by controllers. Lists are compared element by
+// element, because they are replaced as a whole.
+func diffFields(observed, desired map[string]any) ([]fieldChange, error) {
+	o, err := normalize(observed)
+	if err != nil {
+		return nil, err
+	}
+	d, err := normalize(desired)
+	if err != nil {
+		return nil, err
+	}
+	delete(o, "status")
+	delete(d, "status")
+
+	var changes []fieldChange
+	diffValue("", o, d, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// normalize round trips the supplied object through JSON, so that values that
+// are equal once serialized (e.g. int64(1) and float64(1)) compare as equal.
+func normalize(obj map[string]any) (map[string]any, error) {
+	out := map[string]any{}
+	if obj == nil {
+		return out, nil
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+func diffValue(path string, observed, desired any, changes *[]fieldChange) {
+	switch d := desired.(type) {
+	case map[string]any:
+		o, ok := observed.(map[string]any)
+		if !ok {
+			break
+		}
+		for k, dv := range d {
+			diffValue(joinPath(path, k), o[k], dv, changes)
+		}
+		return
+	case []any:
+		o, ok := observed.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(o) || i < len(d); i++ {
+			var ov, dv any
+			if i < len(o) {
+				ov = o[i]
+			}
+			if i < len(d) {
+				dv = d[i]
+			}
+			if dv == nil {
+				*changes = append(*changes, fieldChange{Path: indexPath(path, i), Old: ov})
+				continue
+			}
+			diffValue(indexPath(path, i), ov, dv, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(observed, desired) {
+		*changes = append(*changes, fieldChange{Path: path, Old: observed, New: desired})
+	}
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// observedComposed returns the content of the named observed composed
+// resource, or nil if it isn't observed.
+func observedComposed(req *fnv1.RunFunctionRequest, name resource.Name) (map[string]any, error) {
+	observed, err := request.GetObservedComposedResources(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get observed composed resources from %T", req)
+	}
+	oc, ok := observed[name]
+	if !ok {
+		return nil, nil
+	}
+	return oc.Resource.UnstructuredContent(), nil
+}
+
+// reportChanges reports the fields of an observed NodePool, or NodeGroup, that
+// composing the desired one changes. Changes that drift existing nodes are
+// reported as a warning if warnOnDrift is true. Nothing is reported for a
+// resource that isn't observed yet.
+func (f *Function) reportChanges(rsp *fnv1.RunFunctionResponse, observed, desired map[string]any, name string, warnOnDrift bool) error {
+	if observed == nil {
+		return nil
+	}
+	kind, _ := desired["kind"].(string)
+	changes, err := diffFields(observed, desired)
+	if err != nil {
+		return errors.Wrapf(err, "cannot diff observed and desired %s", kind)
+	}
+
+	var drift, other []string
+	for _, c := range changes {
+		f.log.Debug("Composed field changed", "kind", kind, "name", name, "path", c.Path, "old", jsonValue(c.Old), "new", jsonValue(c.New), "drift", c.Drifts())
+		if warnOnDrift && c.Drifts() {
+			drift = append(drift, c.String())
+			continue
+		}
+		other = append(other, c.String())
+	}
+
+	if len(drift) > 0 {
+		response.Warning(rsp, errors.Errorf("%s %q template changes will replace existing nodes, changing %d field(s): %s", kind, name, len(drift), strings.Join(drift, "; "))).
+			TargetCompositeAndClaim()
+	}
+	if len(other) > 0 {
+		response.Normalf(rsp, "%s %q changes %d field(s): %s", kind, name, len(other), strings.Join(other, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffFields(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		observed map[string]any
+		desired  map[string]any
+		want     []fieldChange
+	}{
+		"Identical": {
+			reason:   "Identical objects should have no changes",
+			observed: map[string]any{"spec": map[string]any{"replicas": float64(1)}},
+			desired:  map[string]any{"spec": map[string]any{"replicas": int64(1)}},
+		},
+		"IgnoreUnsetAndStatus": {
+			reason:   "Fields only set by the API server and status should not be reported",
+			observed: map[string]any{"metadata": map[string]any{"uid": "a"}, "status": map[string]any{"ready": true}},
+			desired:  map[string]any{"metadata": map[string]any{}, "status": map[string]any{"ready": false}},
+		},
+		"Changed": {
+			reason:   "Changed, added and removed list elements should be reported in path order",
+			observed: map[string]any{"spec": map[string]any{"values": []any{"m", "c"}, "size": "large"}},
+			desired:  map[string]any{"spec": map[string]any{"values": []any{"r"}, "size": "xlarge", "zone": "a"}},
+			want: []fieldChange{
+				{Path: "spec.size", Old: "large", New: "xlarge"},
+				{Path: "spec.values[0]", Old: "m", New: "r"},
+				{Path: "spec.values[1]", Old: "c"},
+				{Path: "spec.zone", New: "a"},
+			},
+		},
+		"NotObserved": {
+			reason:  "Every desired field should be reported when nothing is observed",
+			desired: map[string]any{"spec": map[string]any{"size": "large"}},
+			want: []fieldChange{
+				{Path: "spec", New: map[string]any{"size": "large"}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := diffFields(tc.observed, tc.desired)
+			if err != nil {
+				t.Fatalf("diffFields(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\ndiffFields(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
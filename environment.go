@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	corev1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// extraResourceEnvironment is the key under which the Function requests the
+// EnvironmentConfig of the XR's environment tier.
+const extraResourceEnvironment = "environment"
+
+// defaultEnvironmentConfigAPIVersion is the API version the Function requests
+// EnvironmentConfigs at when the Input doesn't say.
+const defaultEnvironmentConfigAPIVersion = "apiextensions.crossplane.io/v1beta1"
+
+// An environmentTier is the NodePool configuration of an environment, read
+// from the data of an EnvironmentConfig. Unset fields keep the Function's
+// built in configuration.
+type environmentTier struct {
+	// Limits of the NodePool, before they're clamped to any budget.
+	Limits *environmentLimits `json:"limits,omitempty"`
+
+	// Categories are the instance categories the NodePool uses, instead of
+	// those the Function selects from the provider's offerings.
+	Categories []string `json:"categories,omitempty"`
+
+	// Disruption settings of the NodePool.
+	Disruption *environmentDisruption `json:"disruption,omitempty"`
+
+	// Taints applied to nodes.
+	Taints []corev1.Taint `json:"taints,omitempty"`
+}
+
+// environmentLimits are the limits of an environment tier.
+type environmentLimits struct {
+	CPU    *k8sresource.Quantity `json:"cpu,omitempty"`
+	Memory *k8sresource.Quantity `json:"memory,omitempty"`
+}
+
+// environmentDisruption are the disruption settings of an environment tier.
+type environmentDisruption struct {
+	ConsolidationPolicy karpenterv1.ConsolidationPolicy `json:"consolidationPolicy,omitempty"`
+	ConsolidateAfter    *karpenterv1.NillableDuration   `json:"consolidateAfter,omitempty"`
+	ExpireAfter         *karpenterv1.NillableDuration   `json:"expireAfter,omitempty"`
+}
+
+// environmentConfigRequirement returns a selector for the EnvironmentConfig of
+// the supplied environment.
+func environmentConfigRequirement(ref *v1beta1.EnvironmentConfigReference, environment string) *fnv1.ResourceSelector {
+	return &fnv1.ResourceSelector{
+		ApiVersion: withDefault(ref.APIVersion, defaultEnvironmentConfigAPIVersion),
+		Kind:       "EnvironmentConfig",
+		Match:      &fnv1.ResourceSelector_MatchName{MatchName: ref.NamePrefix + environment},
+	}
+}
+
+// loadEnvironmentTier returns the tier of the supplied environment, read from
+// the EnvironmentConfig the supplied reference names. It requires the
+// EnvironmentConfig as an extra resource, and returns false if Crossplane
+// hasn't fetched it yet. It returns a nil tier if ref is nil, or if an
+// optional EnvironmentConfig doesn't exist.
+func (f *Function) loadEnvironmentTier(rsp *fnv1.RunFunctionResponse, ref *v1beta1.EnvironmentConfigReference, environment string, extra map[string][]resource.Extra) (*environmentTier, bool, error) {
+	if ref == nil {
+		return nil, true, nil
+	}
+	sel := environmentConfigRequirement(ref, environment)
+	requireExtraResource(rsp, extraResourceEnvironment, sel)
+	ecs, ok := extra[extraResourceEnvironment]
+	switch {
+	case !ok:
+		f.log.Debug("Waiting for EnvironmentConfig", "name", sel.GetMatchName())
+		return nil, false, nil
+	case len(ecs) > 0:
+		t, err := tierFromEnvironmentConfig(ecs[0])
+		return t, err == nil, err
+	case !ref.Optional:
+		return nil, false, errors.Errorf("cannot find EnvironmentConfig %q for spec.CxEnv %q", sel.GetMatchName(), environment)
+	}
+	return nil, true, nil
+}
+
+// tierFromEnvironmentConfig returns the environment tier held by the data of
+// the supplied EnvironmentConfig. Unknown fields are an error, so that typos
+// don't silently keep the built in configuration.
+func tierFromEnvironmentConfig(ec resource.Extra) (*environmentTier, error) {
+	data, _, err := unstructured.NestedMap(ec.Resource.Object, "data")
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read data of EnvironmentConfig %q", ec.Resource.GetName())
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot marshal data of EnvironmentConfig %q", ec.Resource.GetName())
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	t := &environmentTier{}
+	if err := d.Decode(t); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse data of EnvironmentConfig %q", ec.Resource.GetName())
+	}
+	return t, nil
+}
+
+// Apply the tier's disruption settings and taints to the supplied NodePool.
+func (t *environmentTier) Apply(np *karpenterv1.NodePool) {
+	if t == nil {
+		return
+	}
+	if d := t.Disruption; d != nil {
+		if d.ConsolidationPolicy != "" {
+			np.Spec.Disruption.ConsolidationPolicy = d.ConsolidationPolicy
+		}
+		if d.ConsolidateAfter != nil {
+			np.Spec.Disruption.ConsolidateAfter = *d.ConsolidateAfter
+		}
+		if d.ExpireAfter != nil {
+			np.Spec.Template.Spec.ExpireAfter = *d.ExpireAfter
+		}
+	}
+	np.Spec.Template.Spec.Taints = append(np.Spec.Template.Spec.Taints, t.Taints...)
+}
+
+// NodePoolLimits returns the CPU and memory limits of a NodePool in the supplied
+// environment, or the tier's limits if it sets them.
+func (t *environmentTier) NodePoolLimits(environment string) (cpu, memory k8sresource.Quantity) {
+	if environment == "production" {
+		cpu = k8sresource.MustParse("2000m")
+		memory = k8sresource.MustParse("2000Mi")
+	} else {
+		cpu = k8sresource.MustParse("1000m")
+		memory = k8sresource.MustParse("1000Mi")
+	}
+	if t != nil && t.Limits != nil {
+		if t.Limits.CPU != nil {
+			cpu = *t.Limits.CPU
+		}
+		if t.Limits.Memory != nil {
+			memory = *t.Limits.Memory
+		}
+	}
+	return cpu, memory
+}
+
+// NodeTaints returns the tier's taints.
+func (t *environmentTier) NodeTaints() []corev1.Taint {
+	if t == nil {
+		return nil
+	}
+	return t.Taints
+}
+
+// eksTaintEffects are the EKS API formats of Kubernetes taint effects.
+var eksTaintEffects = map[corev1.TaintEffect]string{
+	corev1.TaintEffectNoSchedule:       "NO_SCHEDULE",
+	corev1.TaintEffectNoExecute:        "NO_EXECUTE",
+	corev1.TaintEffectPreferNoSchedule: "PREFER_NO_SCHEDULE",
+}
+
+// NodeGroupTaints returns the tier's taints in EKS API format.
+func (t *environmentTier) NodeGroupTaints() []v1beta1.Taint {
+	if t == nil {
+		return nil
+	}
+	out := make([]v1beta1.Taint, len(t.Taints))
+	for i, tn := range t.Taints {
+		out[i] = v1beta1.Taint{Key: tn.Key, Value: tn.Value, Effect: eksTaintEffects[tn.Effect]}
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTierFromEnvironmentConfig(t *testing.T) {
+	type want struct {
+		categories []string
+		cpu        string
+		err        string
+	}
+
+	cases := map[string]struct {
+		reason string
+		data   map[string]any
+		want   want
+	}{
+		"Tier": {
+			reason: "The tier's fields should be read from the EnvironmentConfig's data.",
+			data: map[string]any{
+				"limits":     map[string]any{"cpu": "16"},
+				"categories": []any{"m", "r"},
+			},
+			want: want{categories: []string{"m", "r"}, cpu: "16"},
+		},
+		"Empty": {
+			reason: "An EnvironmentConfig without data should keep the built in tier.",
+			want:   want{},
+		},
+		"UnknownField": {
+			reason: "A misspelled field should be an error rather than silently ignored.",
+			data:   map[string]any{"limit": map[string]any{"cpu": "16"}},
+			want:   want{err: `cannot parse data of EnvironmentConfig "production": json: unknown field "limit"`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			u := &unstructured.Unstructured{Object: map[string]any{}}
+			u.SetName("production")
+			if tc.data != nil {
+				u.Object["data"] = tc.data
+			}
+			tier, err := tierFromEnvironmentConfig(resource.Extra{Resource: u})
+			got := want{}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if tier != nil {
+				got.categories = tier.Categories
+				if tier.Limits != nil && tier.Limits.CPU != nil {
+					got.cpu = tier.Limits.CPU.String()
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ntierFromEnvironmentConfig(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
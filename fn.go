@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"strings"
 
 	"github.com/crossplane/function-nodepools/input/v1beta1"
 	"github.com/crossplane/function-sdk-go/errors"
@@ -11,35 +12,94 @@ import (
 	"github.com/crossplane/function-sdk-go/resource"
 	"github.com/crossplane/function-sdk-go/resource/composed"
 	"github.com/crossplane/function-sdk-go/response"
-	corev1 "k8s.io/api/core/v1"
-	k8sresource "k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// Function returns whatever response you ask it to.
-type Function struct {
-	fnv1.UnimplementedFunctionRunnerServiceServer
+// ec2API is the subset of the EC2 API used by the Function.
+type ec2API interface {
+	DescribeInstanceTypeOfferings(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error)
+	DescribeCapacityReservations(ctx context.Context, params *ec2.DescribeCapacityReservationsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeCapacityReservationsOutput, error)
+	DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error)
+	DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+}
 
-	log logging.Logger
+// awsEC2 returns a function that creates EC2 clients for a region, configured
+// using the default AWS SDK credential chain. Clients call the supplied
+// endpoint URL instead of the region's EC2 endpoint if it isn't empty.
+func awsEC2(endpoint string) func(ctx context.Context, region string) (ec2API, error) {
+	return func(ctx context.Context, region string) (ec2API, error) {
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		if err != nil {
+			return nil, err
+		}
+		return ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = &endpoint
+			}
+		}), nil
+	}
+}
+
+// describeInstanceTypeOfferings returns every page of instance type offerings
+// matching the supplied input as one output.
+func describeInstanceTypeOfferings(ctx context.Context, c ec2API, params *ec2.DescribeInstanceTypeOfferingsInput) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	out := &ec2.DescribeInstanceTypeOfferingsOutput{}
+	p := ec2.NewDescribeInstanceTypeOfferingsPaginator(c, params)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out.InstanceTypeOfferings = append(out.InstanceTypeOfferings, page.InstanceTypeOfferings...)
+	}
+	return out, nil
 }
 
-// This function checks if a specific instance type exists in DescribeInstanceTypeOfferingsOutput object
-func doesItanceTypeExists(instanceType string, offerings *ec2.DescribeInstanceTypeOfferingsOutput) bool {
-	if offerings == nil {
-		return false
+// describeCapacityReservations returns every page of capacity reservations
+// matching the supplied input as one output.
+func describeCapacityReservations(ctx context.Context, c ec2API, params *ec2.DescribeCapacityReservationsInput) (*ec2.DescribeCapacityReservationsOutput, error) {
+	out := &ec2.DescribeCapacityReservationsOutput{}
+	p := ec2.NewDescribeCapacityReservationsPaginator(c, params)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out.CapacityReservations = append(out.CapacityReservations, page.CapacityReservations...)
 	}
-	for _, offering := range offerings.InstanceTypeOfferings {
-		if string(offering.InstanceType) == instanceType {
-			return true
+	return out, nil
+}
+
+// describeInstanceTypes returns every page of instance types matching the
+// supplied input as one output.
+func describeInstanceTypes(ctx context.Context, c ec2API, params *ec2.DescribeInstanceTypesInput) (*ec2.DescribeInstanceTypesOutput, error) {
+	out := &ec2.DescribeInstanceTypesOutput{}
+	p := ec2.NewDescribeInstanceTypesPaginator(c, params)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
 		}
+		out.InstanceTypes = append(out.InstanceTypes, page.InstanceTypes...)
 	}
-	return false
+	return out, nil
+}
+
+// Function returns whatever response you ask it to.
+type Function struct {
+	fnv1.UnimplementedFunctionRunnerServiceServer
+
+	log logging.Logger
+
+	// ec2 returns an EC2 client for a region. It defaults to awsEC2("").
+	ec2 func(ctx context.Context, region string) (ec2API, error)
+
+	// azure is the catalog of VM SKUs available in each Azure location. XRs
+	// can't use the Azure provider if it is nil.
+	azure *catalog
 }
 
 // RunFunction runs the Function.
@@ -73,6 +133,23 @@ func (f *Function) RunFunction(ctx context.Context, req *fnv1.RunFunctionRequest
 	response.Normalf(rsp, "I was run with input %q!", in.Example)
 	f.log.Info("I was run!", "input", in.Example)
 
+	// The Function requires every extra resource it needs on its first
+	// call, and waits until Crossplane has fetched them all before it calls
+	// any cloud API. Crossplane calls it again with the extra resources.
+	extra, err := request.GetExtraResources(req)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot get extra resources from %T", req))
+		return rsp, nil
+	}
+
+	// Compile the policy the NodePool must satisfy before it is composed.
+	pol, ready, err := f.loadPolicy(rsp, in.Policy, extra)
+	if err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+	waiting := !ready
+
 	// Get desired composed resources and add the NodePool
 	desired, err := request.GetDesiredComposedResources(req)
 	if err != nil {
@@ -98,112 +175,369 @@ func (f *Function) RunFunction(ctx context.Context, req *fnv1.RunFunctionRequest
 		return rsp, nil
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(awsRegion))
+	// Read the XR's environment tier from its EnvironmentConfig.
+	tier, ready, err := f.loadEnvironmentTier(rsp, in.EnvironmentConfig, cxEnv, extra)
+	if err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+	waiting = waiting || !ready
+
+	// The XR selects the Karpenter cloud provider its nodes run on.
+	providerName := providerAWS
+	if v, err := xr.Resource.GetString("spec.Provider"); err == nil && v != "" {
+		providerName = v
+	}
+	prov, err := f.provider(providerName)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "invalid spec.Provider field of %s %q", xr.Resource.GetKind(), xrName))
+		return rsp, nil
+	}
+
+	// Cluster metadata in the pipeline Context takes precedence over the XR.
+	cc, err := readClusterContext(req, in.Context)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot read cluster metadata from the pipeline Context"))
+		return rsp, nil
+	}
+
+	region, regionSource := cc.Region, "Context region"
+	if region == "" {
+		regionSource = prov.RegionField() + " field"
+		if region, err = xr.Resource.GetString(prov.RegionField()); err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "cannot read %s field of %s", prov.RegionField(), xr.Resource.GetKind()))
+			return rsp, nil
+		}
+	}
+
+	karpenterAPIVersion := in.KarpenterAPIVersion
+	if cc.KarpenterAPIVersion != "" {
+		karpenterAPIVersion = v1beta1.KarpenterAPIVersion(cc.KarpenterAPIVersion)
+	}
+	switch karpenterAPIVersion {
+	case v1beta1.KarpenterV1, v1beta1.KarpenterV1Beta1, "":
+	default:
+		response.Fatal(rsp, errors.Errorf("unsupported Karpenter API version %q, must be %q or %q", karpenterAPIVersion, v1beta1.KarpenterV1, v1beta1.KarpenterV1Beta1))
+		return rsp, nil
+	}
+
+	// The XR selects whether Karpenter, EKS Auto Mode, Cluster Autoscaler or
+	// Cluster API scales its nodes, and so what the Function composes.
+	autoscaler := autoscalerKarpenter
+	if v, err := xr.Resource.GetString("spec.Autoscaler"); err == nil && v != "" {
+		autoscaler = v
+	}
+	resourceName, apiVersion, kind := resource.Name("nodepool"), "karpenter.sh/"+string(v1beta1.KarpenterV1), "NodePool"
+	if karpenterAPIVersion == v1beta1.KarpenterV1Beta1 {
+		apiVersion = karpenterV1Beta1APIVersion
+	}
+	var clusterName string
+	switch autoscaler {
+	case autoscalerKarpenter:
+	case autoscalerEKSAutoMode:
+		if prov, err = eksAutoMode(prov); err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "invalid spec.Autoscaler field of %s %q", xr.Resource.GetKind(), xrName))
+			return rsp, nil
+		}
+		if karpenterAPIVersion == v1beta1.KarpenterV1Beta1 {
+			response.Fatal(rsp, errors.Errorf("invalid spec.Autoscaler field of %s %q: %s only serves karpenter.sh/%s NodePools", xr.Resource.GetKind(), xrName, autoscaler, v1beta1.KarpenterV1))
+			return rsp, nil
+		}
+		if in.AutoMode != nil && in.AutoMode.NodeClass != nil && in.NodeClassSelector != nil {
+			response.Fatal(rsp, errors.New("the Input's autoMode.nodeClass and nodeClassSelector are mutually exclusive"))
+			return rsp, nil
+		}
+	case autoscalerClusterAutoscaler, autoscalerClusterAPI:
+		if providerName != providerAWS {
+			response.Fatal(rsp, errors.Errorf("invalid spec.Autoscaler field of %s %q: %s requires provider %q", xr.Resource.GetKind(), xrName, autoscaler, providerAWS))
+			return rsp, nil
+		}
+		if clusterName, err = clusterNameOf(cc, xr); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+		resourceName, apiVersion, kind = resource.Name("nodegroup"), nodeGroupAPIVersion, "NodeGroup"
+		if autoscaler == autoscalerClusterAPI {
+			resourceName, apiVersion, kind = resource.Name("machinedeployment"), machineDeploymentAPIVersion, "MachineDeployment"
+		}
+	default:
+		response.Fatal(rsp, errors.Errorf("invalid spec.Autoscaler field of %s %q: unknown autoscaler %q, must be %q, %q, %q or %q", xr.Resource.GetKind(), xrName, autoscaler, autoscalerKarpenter, autoscalerEKSAutoMode, autoscalerClusterAutoscaler, autoscalerClusterAPI))
+		return rsp, nil
+	}
+	// Delivered NodePools are composed as provider-kubernetes Objects.
+	delivered := in.Delivery != nil && composesNodePool(autoscaler)
+
+	// Launch the nodes of XRs that use capacity reservations in them, using
+	// an EC2NodeClass composed to select them.
+	useReservations := autoscaler == autoscalerKarpenter && usesReservations(in.CapacityReservations, cxEnv)
+	aws, isAWS := prov.(*awsProvider)
+	if useReservations {
+		if !isAWS {
+			response.Fatal(rsp, errors.Errorf("invalid spec.Provider field of %s %q: capacity reservations require provider %q", xr.Resource.GetKind(), xrName, providerAWS))
+			return rsp, nil
+		}
+		if karpenterAPIVersion == v1beta1.KarpenterV1Beta1 {
+			response.Fatal(rsp, errors.Errorf("capacity reservations require karpenter.sh/%s NodePools", v1beta1.KarpenterV1))
+			return rsp, nil
+		}
+	}
+
+	// Name the NodePool so that it doesn't collide with another XR's.
+	poolName, err := composedName(in.Naming, nameData{
+		Name:        xrName,
+		Namespace:   xrNamespace(xr),
+		Environment: cxEnv,
+		Region:      region,
+		Provider:    providerName,
+	})
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot name %s for %s %q", kind, xr.Resource.GetKind(), xrName))
+		return rsp, nil
+	}
+	if n := in.Naming; n != nil && n.CheckExisting {
+		sel := existingRequirement(apiVersion, kind, poolName)
+		if delivered {
+			sel = existingRequirement(objectAPIVersion, objectKind, poolName)
+		}
+		ready, err := f.checkExisting(req, rsp, sel, resourceName, xr, extra)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "cannot compose %s for %s %q", kind, xr.Resource.GetKind(), xrName))
+			return rsp, nil
+		}
+		waiting = waiting || !ready
+	}
+
+	nodeClassRef := prov.NodeClassRef()
+	composeNodeClass := autoscaler == autoscalerEKSAutoMode && in.AutoMode != nil && in.AutoMode.NodeClass != nil
+	if composeNodeClass {
+		nodeClassRef.Name = poolName
+	}
+	if useReservations {
+		nodeClassRef = &karpenterv1.NodeClassReference{Group: "karpenter.k8s.aws", Kind: ec2NodeClassKind, Name: poolName}
+		composeNodeClass = true
+	}
+
+	// Select the NodeClass the NodePool references from existing NodeClasses.
+	if s := in.NodeClassSelector; s != nil && composesNodePool(autoscaler) {
+		if useReservations {
+			response.Fatal(rsp, errors.New("the Input's capacityReservations and nodeClassSelector are mutually exclusive"))
+			return rsp, nil
+		}
+		name, ready, err := f.loadNodeClass(rsp, nodeClassRef.Kind, s, cxEnv, region, extra)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "cannot select NodeClass for %s %q", xr.Resource.GetKind(), xrName))
+			return rsp, nil
+		}
+		nodeClassRef.Name = name
+		waiting = waiting || !ready
+	}
+	if waiting {
+		return rsp, nil
+	}
+	if err := prov.ValidateRegion(ctx, region); err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "invalid %s of %s %q", regionSource, xr.Resource.GetKind(), xrName))
+		return rsp, nil
+	}
+	if useReservations {
+		if err := aws.reportReservations(ctx, rsp, region, in.CapacityReservations.SelectorTerms); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+	}
+
+	// Describe what's offered in the region once; the categories, the
+	// Input's instanceTypes and the fit checks all use it.
+	offered, err := prov.Offerings(ctx, region)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrapf(err, "unable to load SDK config, %v", err))
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+	usedIinstanceCategories := prov.Categories(region, offered)
+	if tier != nil && len(tier.Categories) > 0 {
+		usedIinstanceCategories = tier.Categories
 	}
-	ec2Client := ec2.NewFromConfig(cfg)
 
-	locationFilterName := "location"
-	params := &ec2.DescribeInstanceTypeOfferingsInput{
-		LocationType: types.LocationTypeRegion,
-		Filters: []types.Filter{
-			{
-				Name:   &locationFilterName,
-				Values: []string{awsRegion},
-			},
-		},
+	// Narrow the categories to those with instance types the Input's patterns
+	// allow, and select the allowed instance types explicitly if the
+	// categories can't.
+	var selected *instanceTypeSelection
+	if in.InstanceTypes != nil {
+		if selected, err = filterInstanceTypes(rsp, in.InstanceTypes, prov, autoscaler, cxEnv, region, offered, usedIinstanceCategories); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+		if selected != nil {
+			usedIinstanceCategories = selected.Categories
+		}
 	}
 
-	// This whole part shound be in the function, as forces API interaction during unit tests and we can avoid mocking AWS API
-	// Current plan its serelize the whole InstanceTypeOfferings in to some k8 object by some outside process and use it as input for the function.
+	// Set resource limits based on cxEnv from XR, and clamp them so a fully
+	// scaled out NodePool fits the budget.
+	cpuLimit, memoryLimit := tier.NodePoolLimits(cxEnv)
+	if spend := budgetFor(in.Budget, cxEnv); spend != nil {
+		f.fitBudget(rsp, spend, prov, usedIinstanceCategories, &cpuLimit, &memoryLimit)
+	}
 
-	awsRegion, err := xr.Resource.GetString("spec.AwsRegion")
+	var shapeReqs []karpenterv1.NodeSelectorRequirementWithMinValues
+	if in.Shape != nil {
+		if shapeReqs, err = instanceShape(rsp, in.Shape, prov, autoscaler); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+	}
+
+	// Don't compose a NodePool that can never launch a node, or never launch
+	// a node for the workload.
+	if composesNodePool(autoscaler) && (len(shapeReqs) > 0 || in.Workload != nil) {
+		if err := f.checkLaunchable(ctx, rsp, prov, providerName, in.Workload, shapeReqs, region, offered, usedIinstanceCategories, selected); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+	}
+
+	// Attribution labels the NodePool and tags the instances it launches.
+	labels, err := attributionLabels(in.Attribution, xr, cxEnv)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrapf(err, "cannot read spec.AwsRegion field of %s", xr.Resource.GetKind()))
+		response.Fatal(rsp, errors.Wrapf(err, "cannot attribute %s to %s %q", kind, xr.Resource.GetKind(), xrName))
 		return rsp, nil
 	}
 
-	instanceOffering, err := ec2Client.DescribeInstanceTypeOfferings(ctx, params)
+	var nodePoolResource, machineTemplate *composed.Unstructured
+	switch autoscaler {
+	case autoscalerKarpenter, autoscalerEKSAutoMode:
+		var reqs []karpenterv1.NodeSelectorRequirementWithMinValues
+		if selected != nil && !selected.ByCategory {
+			reqs = append(reqs, instanceTypeRequirement(selected.Allowed))
+		}
+		reqs = append(reqs, shapeReqs...)
+		if useReservations {
+			reqs = append(reqs, reservedCapacityRequirement())
+		}
+		nodePoolResource, err = newNodePool(poolName, nodeClassRef, prov.CategoryLabel(), usedIinstanceCategories, reqs, tier, cpuLimit, memoryLimit, karpenterAPIVersion)
+	case autoscalerClusterAutoscaler:
+		var cfg *v1beta1.NodeGroup
+		if cfg, err = nodeGroupConfig(in.NodeGroup, cc.NodeRoleARN, region, tier); err == nil {
+			nodePoolResource, err = newNodeGroup(poolName, region, clusterName, cfg, usedIinstanceCategories, offered, cpuLimit, memoryLimit)
+		}
+	case autoscalerClusterAPI:
+		nodePoolResource, machineTemplate, err = newMachineDeployment(poolName, clusterName, in.ClusterAPI, usedIinstanceCategories, offered, tier.NodeTaints(), labels, cpuLimit, memoryLimit)
+	}
 	if err != nil {
-		// Fail the function if we can't describe instance type offerings
-		response.Fatal(rsp, errors.Wrapf(err, "unable to describe instance type offerings"))
-		f.log.Info("unable to describe instance type offerings")
-
-		return rsp, nil
-	}
-
-	usedIinstanceCategories := []string{"m"}
-	// Check if t3.medium is available
-	checkInstanceType := "c8g.16xlarge"
-	if doesItanceTypeExists(checkInstanceType, instanceOffering) {
-		// Use t3.medium for the NodePool
-		f.log.Info(checkInstanceType + " instance type is available in " + awsRegion)
-		usedIinstanceCategories = append(usedIinstanceCategories, "c")
-	} else {
-		// Fall back to a different instance type
-		f.log.Info(checkInstanceType + " instance type is not available in " + awsRegion + ", using default")
-	}
-
-	// Set resource limits based on cxEnv from XR
-	var cpuLimit, memoryLimit string
-	if cxEnv == "production" {
-		cpuLimit = "2000m"
-		memoryLimit = "2000Mi"
-	} else {
-		cpuLimit = "1000m"
-		memoryLimit = "1000Mi"
-	}
-
-	// Create NodePool using Karpenter struct
-	nodePool := &karpenterv1.NodePool{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: xrName,
-		},
-		Spec: karpenterv1.NodePoolSpec{
-			Limits: karpenterv1.Limits{
-				corev1.ResourceCPU:    k8sresource.MustParse(cpuLimit),
-				corev1.ResourceMemory: k8sresource.MustParse(memoryLimit),
-			},
-			Disruption: karpenterv1.Disruption{
-				ConsolidationPolicy: karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized,
-			},
-			Template: karpenterv1.NodeClaimTemplate{
-				Spec: karpenterv1.NodeClaimTemplateSpec{
-					NodeClassRef: &karpenterv1.NodeClassReference{
-						Group: "karpenter.sh",
-						Kind:  "EC2NodeClass",
-						Name:  "default2",
-					},
-					Requirements: []karpenterv1.NodeSelectorRequirementWithMinValues{
-						{
-							NodeSelectorRequirement: corev1.NodeSelectorRequirement{
-								Key:      "karpenter.k8s.aws/instance-category",
-								Operator: "In",
-								Values:   usedIinstanceCategories,
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	karpenterSchemeGroupVersion := schema.GroupVersion{
-		Group:   "karpenter.sh",
-		Version: "v1",
-	}
-
-	composed.Scheme.AddKnownTypes(karpenterSchemeGroupVersion, &karpenterv1.NodePool{})
-	// Convert NodePool to composed.Unstructured
-	nodePoolResource, err := composed.From(nodePool)
+		response.Fatal(rsp, errors.Wrapf(err, "cannot compose %s in %s", kind, region))
+		return rsp, nil
+	}
+
+	// Attribute the NodePool, and the nodes it launches, to the XR.
+	if err := attribute(nodePoolResource, xr, labels); err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+
+	// Refuse to compose a NodePool that fails policy.
+	if violations := pol.Evaluate(nodePoolResource.UnstructuredContent(), xr.Resource.UnstructuredContent(), cxEnv); len(violations) > 0 {
+		err := errors.Errorf("%s %q violates %d policy rule(s): %s", nodePoolResource.GetKind(), poolName, len(violations), strings.Join(violations, "; "))
+		if in.Mode != v1beta1.ModeAudit {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+		response.Warning(rsp, err).TargetCompositeAndClaim()
+	}
+
+	// Tell downstream functions what the Function decided on.
+	summary := nodePoolSummary(nodePoolResource, providerName, region, autoscaler, usedIinstanceCategories, cpuLimit, memoryLimit)
+	if err := writeSummary(rsp, in.Context, summary); err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+
+	// A delivered NodePool is observed through the Object that delivers it.
+	observedNodePool, err := observedComposed(req, resourceName)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrapf(err, "cannot convert %T to %T", nodePool, &composed.Unstructured{}))
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+	if delivered {
+		observedNodePool = unwrapObject(observedNodePool)
+	}
+
+	// Only report the NodePool in audit mode, without composing it. Observed
+	// composed resources stay desired as they are, so nothing is deleted.
+	if in.Mode == v1beta1.ModeAudit {
+		if err := f.audit(rsp, observedNodePool, nodePoolResource); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+		observed, err := request.GetObservedComposedResources(req)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "cannot get observed composed resources from %T", req))
+			return rsp, nil
+		}
+		keepObserved(desired, observed)
+		if err := response.SetDesiredComposedResources(rsp, desired); err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "cannot set desired composed resources in %T", rsp))
+			return rsp, nil
+		}
+		response.ConditionTrue(rsp, "FunctionSuccess", "Success").
+			TargetCompositeAndClaim()
 		return rsp, nil
 	}
 
-	// Add the NodePool to desired composed resources
-	desired[resource.Name("nodepool")] = &resource.DesiredComposed{Resource: nodePoolResource}
+	// Report how the NodePool changes before composing it.
+	if err := f.reportChanges(rsp, observedNodePool, nodePoolResource.UnstructuredContent(), poolName, in.Diff != nil && in.Diff.WarnOnDrift); err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+
+	// Add the NodePool, and the NodeClass and NodeOverlays composed for it,
+	// to desired composed resources
+	desired[resourceName] = &resource.DesiredComposed{Resource: nodePoolResource}
+	supporting := map[resource.Name]*composed.Unstructured{}
+	var nodeClass *composed.Unstructured
+	switch {
+	case useReservations:
+		nodeClass, err = newEC2NodeClass(nodeClassRef.Name, in.CapacityReservations.NodeClass, in.CapacityReservations.SelectorTerms)
+	case composeNodeClass:
+		nodeClass, err = newAutoModeNodeClass(nodeClassRef.Name, in.AutoMode.NodeClass)
+	}
+	if err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+	if nodeClass != nil {
+		// Karpenter tags the EC2 instances it launches with the NodeClass's
+		// tags, so attribute them like the NodePool.
+		if err := attribute(nodeClass, xr, labels); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+		supporting[resourceNameNodeClass] = nodeClass
+	}
+	if len(in.NodeOverlays) > 0 && composesNodePool(autoscaler) {
+		if karpenterAPIVersion == v1beta1.KarpenterV1Beta1 {
+			response.Fatal(rsp, errors.Errorf("NodeOverlays require karpenter.sh/%s NodePools", v1beta1.KarpenterV1))
+			return rsp, nil
+		}
+		overlays, err := newNodeOverlays(in.NodeOverlays, nodePoolResource, prov.FamilyLabel(), labels)
+		if err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+		for name, o := range overlays {
+			supporting[name] = o
+		}
+	}
+	if machineTemplate != nil {
+		supporting[resourceNameAWSMachineTemplate] = machineTemplate
+	}
+	for name, res := range supporting {
+		desired[name] = &resource.DesiredComposed{Resource: res}
+	}
+	if delivered {
+		if err := deliver(desired, in.Delivery, xr, resourceName, observedNodePool, supporting); err != nil {
+			response.Fatal(rsp, err)
+			return rsp, nil
+		}
+	}
 
 	// Set the desired composed resources in the response
 	if err := response.SetDesiredComposedResources(rsp, desired); err != nil {
@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/crossplane/function-sdk-go/logging"
 	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
 	"github.com/crossplane/function-sdk-go/resource"
@@ -17,8 +21,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
 	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/internal/fakeec2"
 )
 
 // testLogSink implements logr.LogSink for testing
@@ -44,6 +52,14 @@ func (s *testLogSink) WithName(name string) logr.LogSink {
 	return s
 }
 
+// offerings are the instance types the tests pretend AWS and Azure offer.
+var offerings = &catalog{Regions: map[string]catalogRegion{
+	"af-south-1": {InstanceTypes: []string{"m5.large", "c5.large"}},
+	"us-east-1":  {InstanceTypes: []string{"m5.large", "c5.large", "c8g.16xlarge"}},
+}, Locations: map[string]catalogLocation{
+	"westeurope": {SKUs: []string{"Standard_D4s_v5", "Standard_F16s_v2"}},
+}}
+
 func TestRunFunction(t *testing.T) {
 	type args struct {
 		ctx context.Context
@@ -285,23 +301,1354 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
-	}
+		"BudgetClampsLimits": {
+			reason: "The Function should clamp limits that exceed the environment's monthly budget and explain why",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"budget": {
+							"maxMonthlySpend": "1000",
+							"environments": {
+								"production": "50"
+							}
+						}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "AwsRegion": "us-east-1"
+                }
+              }`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  "NodePool limits exceed the monthly budget of $50 for instance categories [m c], clamped cpu 2 -> 1426m",
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    "BudgetCapped",
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  "LimitsClamped",
+							Message: ptr.To("NodePool limits exceed the monthly budget of $50 for instance categories [m c], clamped cpu 2 -> 1426m"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: func() *fnv1.State {
+						nodePool := &karpenterv1.NodePool{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "np1",
+							},
+							Spec: karpenterv1.NodePoolSpec{
+								Limits: karpenterv1.Limits{
+									corev1.ResourceCPU:    k8sresource.MustParse("1426m"),
+									corev1.ResourceMemory: k8sresource.MustParse("2000Mi"),
+								},
+								Disruption: karpenterv1.Disruption{
+									ConsolidationPolicy: karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized,
+								},
+								Template: karpenterv1.NodeClaimTemplate{
+									Spec: karpenterv1.NodeClaimTemplateSpec{
+										NodeClassRef: &karpenterv1.NodeClassReference{
+											Group: "karpenter.sh",
+											Kind:  "EC2NodeClass",
+											Name:  "default2",
+										},
+										Requirements: []karpenterv1.NodeSelectorRequirementWithMinValues{
+											{
+												NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+													Key:      "karpenter.k8s.aws/instance-category",
+													Operator: "In",
+													Values:   []string{"m", "c"},
+												},
+											},
+										},
+									},
+								},
+							},
+						}
 
-	for name, tc := range cases {
-		t.Run(name, func(t *testing.T) {
-			// Create a verbose logger for testing
-			logger := logr.New(&testLogSink{t: t})
-			f := &Function{log: logging.NewLogrLogger(logger)}
-			ctx := context.Background()
-			rsp, err := f.RunFunction(ctx, tc.args.req)
+						nodePoolResource, err := composed.From(nodePool)
+						if err != nil {
+							t.Fatalf("cannot convert %T to %T: %v", nodePool, &composed.Unstructured{}, err)
+						}
 
-			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
-			}
+						nodePoolStruct, err := resource.AsStruct(nodePoolResource)
+						if err != nil {
+							t.Fatalf("cannot convert %T to structpb.Struct: %v", nodePoolResource, err)
+						}
 
-			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
-			}
-		})
+						return &fnv1.State{
+							Resources: map[string]*fnv1.Resource{
+								"nodepool": {
+									Resource: nodePoolStruct,
+								},
+							},
+						}
+					}(),
+				},
+			},
+		},
+		"UnknownRegion": {
+			reason: "The Function should return a fatal result naming spec.AwsRegion when the XR's region is misspelled",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "AwsRegion": "us-est-1"
+                }
+              }`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid spec.AwsRegion field of XNodePool \"np1\": unknown AWS region \"us-est-1\", did you mean \"us-east-1\"?",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"UndescribedShape": {
+			reason: "The Function should return a fatal result that no instance types are described, not that none satisfy the Input's shape",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"shape": {"minVCPUs": 2}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "AwsRegion": "af-south-1"
+                }
+              }`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "cannot check the Input's shape: no instance types of categories [m] are described in af-south-1",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"UnknownProvider": {
+			reason: "The Function should return a fatal result naming spec.Provider when the XR selects an unknown provider",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "Provider": "gcp",
+                  "AwsRegion": "us-east-1"
+                }
+              }`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid spec.Provider field of XNodePool \"np1\": unknown provider \"gcp\", must be \"aws\" or \"azure\"",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"UnknownAzureLocation": {
+			reason: "The Function should return a fatal result naming spec.AzureLocation when the SKU catalog doesn't know the XR's location",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "Provider": "azure",
+                  "AzureLocation": "westeuroop"
+                }
+              }`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid spec.AzureLocation field of XNodePool \"np1\": Azure location \"westeuroop\" is not in the SKU catalog",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"InvalidPolicy": {
+			reason: "The Function should return a fatal result when a policy rule doesn't compile",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"policy": {
+							"rules": [
+								{
+									"name": "production-categories",
+									"expression": "environment != 'production' || nodePool.spec.template.spec.requirements.all(r, r.key != 'karpenter.k8s.aws/instance-category' || r.values.all(v, v in ['m', 'r']))",
+									"message": "production NodePools may only use m and r instances"
+								},
+								{
+									"name": "max-cpu",
+									"expression": "quantity(nodePool.spec.limits.cpu) <= 1"
+								},
+								{
+									"name": "named-after-xr",
+									"expression": "nodePool.metadata.name == xr.metadata.name"
+								}
+							]
+						}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "AwsRegion": "us-east-1"
+                }
+              }`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "invalid policy: cannot compile policy rule \"max-cpu\": ERROR: <input>:1:9: undeclared reference to 'quantity' (in container '')\n | quantity(nodePool.spec.limits.cpu) <= 1\n | ........^",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"PolicyConfigMapRequired": {
+			reason: "The Function should request the policy ConfigMap and wait for Crossplane to supply it",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"policy": {
+							"configMapRef": {
+								"name": "nodepool-policy"
+							}
+						}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "AwsRegion": "us-east-1"
+                }
+              }`),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Requirements: &fnv1.Requirements{
+						ExtraResources: map[string]*fnv1.ResourceSelector{
+							"policy": {
+								ApiVersion: "v1",
+								Kind:       "ConfigMap",
+								Match:      &fnv1.ResourceSelector_MatchName{MatchName: "nodepool-policy"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"PolicyViolation": {
+			reason: "The Function should refuse to compose a NodePool that violates inline or ConfigMap policy rules, listing every violated rule",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"policy": {
+							"rules": [
+								{
+									"name": "production-categories",
+									"expression": "environment != 'production' || nodePool.spec.template.spec.requirements.all(r, r.key != 'karpenter.k8s.aws/instance-category' || r.values.all(v, v in ['m', 'r']))",
+									"message": "production NodePools may only use m and r instances"
+								}
+							],
+							"configMapRef": {
+								"name": "nodepool-policy"
+							}
+						}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "production",
+                  "AwsRegion": "us-east-1"
+                }
+              }`),
+						},
+					},
+					ExtraResources: map[string]*fnv1.Resources{
+						"policy": {
+							Items: []*fnv1.Resource{
+								{
+									Resource: resource.MustStructJSON(`{
+										"apiVersion": "v1",
+										"kind": "ConfigMap",
+										"metadata": {
+											"name": "nodepool-policy"
+										},
+										"data": {
+											"named-after-xr": "nodePool.metadata.name == xr.metadata.name",
+											"no-spot": "!nodePool.spec.template.spec.requirements.exists(r, r.key == 'karpenter.sh/capacity-type' && 'spot' in r.values)",
+											"cpu-below-1k": "nodePool.spec.limits.cpu == '1'"
+										}
+									}`),
+								},
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Message:  "NodePool \"np1\" violates 2 policy rule(s): production-categories: production NodePools may only use m and r instances; cpu-below-1k: nodePool.spec.limits.cpu == '1' evaluated to false",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Requirements: &fnv1.Requirements{
+						ExtraResources: map[string]*fnv1.ResourceSelector{
+							"policy": {
+								ApiVersion: "v1",
+								Kind:       "ConfigMap",
+								Match:      &fnv1.ResourceSelector_MatchName{MatchName: "nodepool-policy"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"AuditMode": {
+			reason: "The Function should report the NodePool and its diff against the observed NodePool without changing it, keeping the observed NodePool desired as it is so that it isn't deleted",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"mode": "Audit"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "development",
+                  "AwsRegion": "af-south-1"
+                }
+              }`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"nodepool": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.sh/v1",
+									"kind": "NodePool",
+									"metadata": {
+										"name": "np1",
+										"uid": "6a1b4c6e"
+									},
+									"spec": {
+										"disruption": {
+											"consolidationPolicy": "WhenEmptyOrUnderutilized",
+											"consolidateAfter": "0s"
+										},
+										"limits": {
+											"cpu": "2",
+											"memory": "1000Mi"
+										},
+										"template": {
+											"metadata": {},
+											"spec": {
+												"nodeClassRef": {
+													"group": "karpenter.sh",
+													"kind": "EC2NodeClass",
+													"name": "default2"
+												},
+												"requirements": [
+													{
+														"key": "karpenter.k8s.aws/instance-category",
+														"operator": "In",
+														"values": ["m", "c"]
+													}
+												]
+											}
+										}
+									},
+									"status": {
+										"resources": {
+											"cpu": "0"
+										}
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `Audit mode: would compose NodePool "np1" with spec {"disruption":{"consolidateAfter":"Never","consolidationPolicy":"WhenEmptyOrUnderutilized"},"limits":{"cpu":"1","memory":"1000Mi"},"template":{"spec":{"expireAfter":"Never","nodeClassRef":{"group":"karpenter.sh","kind":"EC2NodeClass","name":"default2"},"requirements":[{"key":"karpenter.k8s.aws/instance-category","operator":"In","values":["m"]}]}}}`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `Audit mode: composing NodePool "np1" would change 4 field(s): spec.disruption.consolidateAfter: "0s" -> "Never"; spec.limits.cpu: "2" -> "1"; spec.template.spec.expireAfter: <none> -> "Never"; spec.template.spec.requirements[0].values[1]: "c" -> <none>`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"status": {
+									"nodePoolAudit": {
+										"name": "np1",
+										"spec": {
+											"disruption": {
+												"consolidateAfter": "Never",
+												"consolidationPolicy": "WhenEmptyOrUnderutilized"
+											},
+											"limits": {
+												"cpu": "1",
+												"memory": "1000Mi"
+											},
+											"template": {
+												"spec": {
+													"expireAfter": "Never",
+													"nodeClassRef": {
+														"group": "karpenter.sh",
+														"kind": "EC2NodeClass",
+														"name": "default2"
+													},
+													"requirements": [
+														{
+															"key": "karpenter.k8s.aws/instance-category",
+															"operator": "In",
+															"values": ["m"]
+														}
+													]
+												}
+											}
+										},
+										"changes": [
+											{
+												"path": "spec.disruption.consolidateAfter",
+												"observed": "\"0s\"",
+												"desired": "\"Never\""
+											},
+											{
+												"path": "spec.limits.cpu",
+												"observed": "\"2\"",
+												"desired": "\"1\""
+											},
+											{
+												"path": "spec.template.spec.expireAfter",
+												"observed": "<none>",
+												"desired": "\"Never\""
+											},
+											{
+												"path": "spec.template.spec.requirements[0].values[1]",
+												"observed": "\"c\"",
+												"desired": "<none>"
+											}
+										]
+									}
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"nodepool": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.sh/v1",
+									"kind": "NodePool",
+									"metadata": {
+										"name": "np1"
+									},
+									"spec": {
+										"disruption": {
+											"consolidationPolicy": "WhenEmptyOrUnderutilized",
+											"consolidateAfter": "0s"
+										},
+										"limits": {
+											"cpu": "2",
+											"memory": "1000Mi"
+										},
+										"template": {
+											"metadata": {},
+											"spec": {
+												"nodeClassRef": {
+													"group": "karpenter.sh",
+													"kind": "EC2NodeClass",
+													"name": "default2"
+												},
+												"requirements": [
+													{
+														"key": "karpenter.k8s.aws/instance-category",
+														"operator": "In",
+														"values": ["m", "c"]
+													}
+												]
+											}
+										}
+									}
+								}`),
+							},
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"AuditModeKeepsComposedResources": {
+			reason: "The Function should keep every observed composed resource desired in audit mode, without status or server set metadata, and not replace resources an earlier Function desires",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"mode": "Audit"
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "development",
+                  "AwsRegion": "af-south-1"
+                }
+              }`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"nodepool": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.sh/v1",
+									"kind": "NodePool",
+									"metadata": {
+										"name": "np1",
+										"uid": "6a1b4c6e",
+										"resourceVersion": "42",
+										"labels": {
+											"crossplane.io/composite": "np1"
+										}
+									},
+									"spec": {
+										"disruption": {
+											"consolidateAfter": "Never",
+											"consolidationPolicy": "WhenEmptyOrUnderutilized"
+										},
+										"limits": {
+											"cpu": "1",
+											"memory": "1000Mi"
+										},
+										"template": {
+											"spec": {
+												"expireAfter": "Never",
+												"nodeClassRef": {
+													"group": "karpenter.sh",
+													"kind": "EC2NodeClass",
+													"name": "default2"
+												},
+												"requirements": [
+													{
+														"key": "karpenter.k8s.aws/instance-category",
+														"operator": "In",
+														"values": ["m"]
+													}
+												]
+											}
+										}
+									},
+									"status": {
+										"resources": {
+											"cpu": "0"
+										}
+									}
+								}`),
+							},
+							"nodeclass": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.k8s.aws/v1",
+									"kind": "EC2NodeClass",
+									"metadata": {
+										"name": "np1"
+									},
+									"spec": {
+										"role": "observed"
+									}
+								}`),
+							},
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"nodeclass": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.k8s.aws/v1",
+									"kind": "EC2NodeClass",
+									"metadata": {
+										"name": "np1"
+									},
+									"spec": {
+										"role": "desired"
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `Audit mode: would compose NodePool "np1" with spec {"disruption":{"consolidateAfter":"Never","consolidationPolicy":"WhenEmptyOrUnderutilized"},"limits":{"cpu":"1","memory":"1000Mi"},"template":{"spec":{"expireAfter":"Never","nodeClassRef":{"group":"karpenter.sh","kind":"EC2NodeClass","name":"default2"},"requirements":[{"key":"karpenter.k8s.aws/instance-category","operator":"In","values":["m"]}]}}}`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `Audit mode: NodePool "np1" matches the observed NodePool`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"status": {
+									"nodePoolAudit": {
+										"name": "np1",
+										"spec": {
+												"disruption": {
+													"consolidateAfter": "Never",
+													"consolidationPolicy": "WhenEmptyOrUnderutilized"
+												},
+												"limits": {
+													"cpu": "1",
+													"memory": "1000Mi"
+												},
+												"template": {
+													"spec": {
+														"expireAfter": "Never",
+														"nodeClassRef": {
+															"group": "karpenter.sh",
+															"kind": "EC2NodeClass",
+															"name": "default2"
+														},
+														"requirements": [
+															{
+																"key": "karpenter.k8s.aws/instance-category",
+																"operator": "In",
+																"values": ["m"]
+															}
+														]
+													}
+												}
+											},
+										"changes": []
+									}
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"nodepool": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.sh/v1",
+									"kind": "NodePool",
+									"metadata": {
+										"name": "np1",
+										"labels": {
+											"crossplane.io/composite": "np1"
+										}
+									},
+									"spec": {
+										"disruption": {
+											"consolidateAfter": "Never",
+											"consolidationPolicy": "WhenEmptyOrUnderutilized"
+										},
+										"limits": {
+											"cpu": "1",
+											"memory": "1000Mi"
+										},
+										"template": {
+											"spec": {
+												"expireAfter": "Never",
+												"nodeClassRef": {
+													"group": "karpenter.sh",
+													"kind": "EC2NodeClass",
+													"name": "default2"
+												},
+												"requirements": [
+													{
+														"key": "karpenter.k8s.aws/instance-category",
+														"operator": "In",
+														"values": ["m"]
+													}
+												]
+											}
+										}
+									}
+								}`),
+							},
+							"nodeclass": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.k8s.aws/v1",
+									"kind": "EC2NodeClass",
+									"metadata": {
+										"name": "np1"
+									},
+									"spec": {
+										"role": "desired"
+									}
+								}`),
+							},
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ReportChanges": {
+			reason: "The Function should report changes to an observed NodePool, warning about changes that drift nodes when asked to",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"example": "Hello, world",
+						"diff": {
+							"warnOnDrift": true
+						}
+					}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+                "apiVersion": "example.crossplane.io/v1alpha1",
+                "kind": "XNodePool",
+                "metadata": {
+                  "name": "np1"
+                },
+                "spec": {
+                  "CxEnv": "development",
+                  "AwsRegion": "af-south-1"
+                }
+              }`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"nodepool": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.sh/v1",
+									"kind": "NodePool",
+									"metadata": {
+										"name": "np1"
+									},
+									"spec": {
+										"disruption": {
+											"consolidationPolicy": "WhenEmptyOrUnderutilized",
+											"consolidateAfter": "Never"
+										},
+										"limits": {
+											"cpu": "2",
+											"memory": "1000Mi"
+										},
+										"template": {
+											"spec": {
+												"expireAfter": "Never",
+												"nodeClassRef": {
+													"group": "karpenter.sh",
+													"kind": "EC2NodeClass",
+													"name": "default2"
+												},
+												"requirements": [
+													{
+														"key": "karpenter.k8s.aws/instance-category",
+														"operator": "In",
+														"values": ["m", "c"]
+													}
+												]
+											}
+										}
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "I was run with input \"Hello, world\"!",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  `NodePool "np1" template changes will replace existing nodes, changing 1 field(s): spec.template.spec.requirements[0].values[1]: "c" -> <none>`,
+							Target:   fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  `NodePool "np1" changes 1 field(s): spec.limits.cpu: "2" -> "1"`,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:   "FunctionSuccess",
+							Status: fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason: "Success",
+							Target: fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"nodepool": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "karpenter.sh/v1",
+									"kind": "NodePool",
+									"metadata": {
+										"name": "np1"
+									},
+									"spec": {
+										"disruption": {
+											"consolidationPolicy": "WhenEmptyOrUnderutilized",
+											"consolidateAfter": "Never"
+										},
+										"limits": {
+											"cpu": "1",
+											"memory": "1000Mi"
+										},
+										"template": {
+											"spec": {
+												"expireAfter": "Never",
+												"nodeClassRef": {
+													"group": "karpenter.sh",
+													"kind": "EC2NodeClass",
+													"name": "default2"
+												},
+												"requirements": [
+													{
+														"key": "karpenter.k8s.aws/instance-category",
+														"operator": "In",
+														"values": ["m"]
+													}
+												]
+											}
+										}
+									},
+									"status": {
+										"nodeClassObservedGeneration": 0
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// Create a verbose logger for testing
+			logger := logr.New(&testLogSink{t: t})
+			f := &Function{log: logging.NewLogrLogger(logger), ec2: offerings.client, azure: offerings}
+			ctx := context.Background()
+			rsp, err := f.RunFunction(ctx, tc.args.req)
+
+			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionWithEC2Endpoint(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	fixture, err := fakeec2.LoadFixture("internal/fakeec2/testdata/fixture.yaml")
+	if err != nil {
+		t.Fatalf("LoadFixture(...): %v", err)
+	}
+
+	type want struct {
+		categories []any
+		fatal      string
+	}
+
+	cases := map[string]struct {
+		reason string
+		region string
+		faults []fakeec2.Fault
+		want   want
+	}{
+		"OfferedCategory": {
+			reason: "The Function should add the c category when the EC2 API offers c8g.16xlarge in the XR's region.",
+			region: "us-east-1",
+			want:   want{categories: []any{"m", "c"}},
+		},
+		"NotOfferedCategory": {
+			reason: "The Function should use only the m category when the EC2 API doesn't offer c8g.16xlarge in the XR's region.",
+			region: "af-south-1",
+			want:   want{categories: []any{"m"}},
+		},
+		"RetriedThrottle": {
+			reason: "The Function should succeed when the EC2 API throttles its first request.",
+			region: "us-east-1",
+			faults: []fakeec2.Fault{{Status: http.StatusServiceUnavailable, Code: "RequestLimitExceeded", Message: "Request limit exceeded."}},
+			want:   want{categories: []any{"m", "c"}},
+		},
+		"DisabledRegion": {
+			reason: "The Function should return a fatal result when the EC2 API rejects the XR's region.",
+			region: "eu-west-1",
+			want:   want{fatal: `unable to describe instance type offerings: operation error EC2: DescribeInstanceTypeOfferings, https response error StatusCode: 401, RequestID: fake-error, api error AuthFailure: region "eu-west-1" is not enabled for this account`},
+		},
+		"MisspelledRegion": {
+			reason: "The Function should suggest the closest region when its partition's EC2 API doesn't know the XR's unlisted region either.",
+			region: "us-est-1",
+			want:   want{fatal: `invalid spec.AwsRegion field of XR "cool-xr": unknown AWS region "us-est-1", did you mean "us-east-1"?`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(fakeec2.New(fixture, fakeec2.WithFaults(tc.faults...)))
+			defer srv.Close()
+
+			req := &fnv1.RunFunctionRequest{
+				Input: resource.MustStructJSON(`{
+					"apiVersion": "template.fn.crossplane.io/v1beta1",
+					"kind": "Input",
+					"example": "Hello, world"
+				}`),
+				Observed: &fnv1.State{
+					Composite: &fnv1.Resource{
+						Resource: resource.MustStructJSON(`{
+							"apiVersion": "example.crossplane.io/v1",
+							"kind": "XR",
+							"metadata": {"name": "cool-xr"},
+							"spec": {"CxEnv": "development", "AwsRegion": "` + tc.region + `"}
+						}`),
+					},
+				},
+			}
+
+			f := &Function{log: logging.NewNopLogger(), ec2: awsEC2(srv.URL)}
+			rsp, err := f.RunFunction(context.Background(), req)
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+
+			fatal := ""
+			for _, r := range rsp.GetResults() {
+				if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+					fatal = r.GetMessage()
+				}
+			}
+			if diff := cmp.Diff(tc.want.fatal, fatal); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want fatal, +got fatal:\n%s\nresults: %v", tc.reason, diff, rsp.GetResults())
+			}
+			if tc.want.fatal != "" {
+				return
+			}
+
+			np := rsp.GetDesired().GetResources()["nodepool"].GetResource().AsMap()
+			reqs, _, _ := unstructured.NestedSlice(np, "spec", "template", "spec", "requirements")
+			if len(reqs) != 1 {
+				t.Fatalf("%s\nf.RunFunction(...): got %d NodePool requirements, want 1", tc.reason, len(reqs))
+			}
+			if diff := cmp.Diff(tc.want.categories, reqs[0].(map[string]any)["values"]); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want categories, +got categories:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// countingEC2 counts the EC2 API calls made with it.
+type countingEC2 struct {
+	ec2API
+	calls map[string]int
+}
+
+func (c countingEC2) DescribeInstanceTypeOfferings(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	c.calls["DescribeInstanceTypeOfferings"]++
+	return c.ec2API.DescribeInstanceTypeOfferings(ctx, params, optFns...)
+}
+
+func (c countingEC2) DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	c.calls["DescribeInstanceTypes"]++
+	return c.ec2API.DescribeInstanceTypes(ctx, params, optFns...)
+}
+
+func TestRunFunctionDescribesOfferingsOnce(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	fixture, err := fakeec2.LoadFixture("internal/fakeec2/testdata/fixture.yaml")
+	if err != nil {
+		t.Fatalf("LoadFixture(...): %v", err)
+	}
+	srv := httptest.NewServer(fakeec2.New(fixture))
+	defer srv.Close()
+
+	calls := map[string]int{}
+	newClient := awsEC2(srv.URL)
+	f := &Function{log: logging.NewNopLogger(), ec2: func(ctx context.Context, region string) (ec2API, error) {
+		calls["client"]++
+		c, err := newClient(ctx, region)
+		return countingEC2{ec2API: c, calls: calls}, err
+	}}
+
+	req := &fnv1.RunFunctionRequest{
+		Input: resource.MustStructJSON(`{
+			"apiVersion": "template.fn.crossplane.io/v1beta1",
+			"kind": "Input",
+			"example": "Hello, world",
+			"instanceTypes": {"deny": ["*.metal"]},
+			"shape": {"minVCPUs": 2},
+			"workload": {"cpu": "1"}
+		}`),
+		Observed: &fnv1.State{
+			Composite: &fnv1.Resource{
+				Resource: resource.MustStructJSON(`{
+					"apiVersion": "example.crossplane.io/v1",
+					"kind": "XR",
+					"metadata": {"name": "cool-xr"},
+					"spec": {"CxEnv": "development", "AwsRegion": "us-east-1"}
+				}`),
+			},
+		},
+	}
+	rsp, err := f.RunFunction(context.Background(), req)
+	if err != nil {
+		t.Fatalf("f.RunFunction(...): %v", err)
+	}
+	for _, r := range rsp.GetResults() {
+		if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+			t.Fatalf("f.RunFunction(...): unexpected fatal result: %s", r.GetMessage())
+		}
+	}
+
+	want := map[string]int{"client": 1, "DescribeInstanceTypeOfferings": 1, "DescribeInstanceTypes": 1}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Errorf("f.RunFunction(...) should create one EC2 client and describe the region's offerings once, instanceTypes, shape and workload notwithstanding: -want, +got:\n%s", diff)
+	}
+}
+
+func TestRunFunctionRequirements(t *testing.T) {
+	req := &fnv1.RunFunctionRequest{
+		Input: resource.MustStructJSON(`{
+			"apiVersion": "template.fn.crossplane.io/v1beta1",
+			"kind": "Input",
+			"example": "Hello, world",
+			"policy": {"configMapRef": {"name": "nodepool-policy"}},
+			"environmentConfig": {"namePrefix": "nodepools-"},
+			"naming": {"checkExisting": true},
+			"nodeClassSelector": {"matchLabels": {"platform.example.org/managed": "true"}}
+		}`),
+		Observed: &fnv1.State{
+			Composite: &fnv1.Resource{
+				Resource: resource.MustStructJSON(`{
+					"apiVersion": "example.crossplane.io/v1alpha1",
+					"kind": "XNodePool",
+					"metadata": {"name": "np1"},
+					"spec": {"CxEnv": "production", "AwsRegion": "us-east-1"}
+				}`),
+			},
+		},
+	}
+	extra := []*unstructured.Unstructured{
+		{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]any{"name": "nodepool-policy"},
+			"data":       map[string]any{"named-after-xr": "nodePool.metadata.name == xr.metadata.name"},
+		}},
+		{Object: map[string]any{
+			"apiVersion": "apiextensions.crossplane.io/v1beta1",
+			"kind":       "EnvironmentConfig",
+			"metadata":   map[string]any{"name": "nodepools-production"},
+			"data":       map[string]any{"limits": map[string]any{"cpu": "8"}},
+		}},
+		{Object: map[string]any{
+			"apiVersion": "karpenter.k8s.aws/v1",
+			"kind":       "EC2NodeClass",
+			"metadata": map[string]any{
+				"name": "production-us-east-1",
+				"labels": map[string]any{
+					"platform.example.org/managed":           "true",
+					"nodepools.fn.crossplane.io/environment": "production",
+					"topology.kubernetes.io/region":          "us-east-1",
+				},
+			},
+		}},
+	}
+
+	// Call the Function like Crossplane does, until its requirements are
+	// satisfied.
+	f := &Function{log: logging.NewNopLogger(), ec2: offerings.client}
+	var rsp *fnv1.RunFunctionResponse
+	for calls := 1; ; calls++ {
+		var err error
+		if rsp, err = f.RunFunction(context.Background(), req); err != nil {
+			t.Fatalf("f.RunFunction(...): %v", err)
+		}
+		next := selectExtraResources(rsp.GetRequirements(), extra)
+		if sameExtraResources(req.GetExtraResources(), next) {
+			break
+		}
+		if calls == 2 {
+			t.Fatalf("f.RunFunction(...): requirements not satisfied after %d calls; the Function should require every extra resource on its first call, got %v", calls, rsp.GetRequirements().GetExtraResources())
+		}
+		req.ExtraResources = next
+	}
+
+	for _, r := range rsp.GetResults() {
+		if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+			t.Fatalf("f.RunFunction(...): unexpected fatal result: %s", r.GetMessage())
+		}
+	}
+	np := rsp.GetDesired().GetResources()["nodepool"].GetResource().AsMap()
+	name, _, _ := unstructured.NestedString(np, "spec", "template", "spec", "nodeClassRef", "name")
+	cpu, _, _ := unstructured.NestedString(np, "spec", "limits", "cpu")
+	if diff := cmp.Diff([]string{"production-us-east-1", "8"}, []string{name, cpu}); diff != "" {
+		t.Errorf("f.RunFunction(...): the NodePool should use the selected NodeClass and the EnvironmentConfig's limits: -want, +got:\n%s", diff)
 	}
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "Update the expected.yaml file of every golden test case.")
+
+// Files of a golden test case. Every file but observed.yaml and
+// extra-resources.yaml is required.
+const (
+	goldenDir            = "testdata/golden"
+	goldenXR             = "xr.yaml"
+	goldenInput          = "input.yaml"
+	goldenOfferings      = "offerings.json"
+	goldenObserved       = "observed.yaml"
+	goldenExtraResources = "extra-resources.yaml"
+	goldenExpected       = "expected.yaml"
+)
+
+// TestGolden runs RunFunction for every directory under testdata/golden, and
+// compares the resulting XR, composed resources and results with the
+// directory's expected.yaml. Run go test -run TestGolden -update to regenerate
+// expected.yaml after an intended change.
+func TestGolden(t *testing.T) {
+	dirs, err := os.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatalf("cannot read golden test cases: %v", err)
+	}
+
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(goldenDir, d.Name())
+		t.Run(d.Name(), func(t *testing.T) {
+			got, err := renderGolden(dir)
+			if err != nil {
+				t.Fatalf("cannot render %s: %v", dir, err)
+			}
+
+			expected := filepath.Join(dir, goldenExpected)
+			if *update {
+				if err := os.WriteFile(expected, got, 0o600); err != nil {
+					t.Fatalf("cannot update %s: %v", expected, err)
+				}
+			}
+
+			want, err := os.ReadFile(expected) //nolint:gosec // Reading test data is intended.
+			if err != nil {
+				t.Fatalf("cannot read %s, run go test -run TestGolden -update to create it: %v", expected, err)
+			}
+			if diff := cmp.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("RunFunction(...) output doesn't match %s, run go test -run TestGolden -update if the change is intended: -want, +got:\n%s", expected, diff)
+			}
+		})
+	}
+}
+
+// renderGolden runs the Function for the golden test case in dir and returns
+// its output as a stream of YAML documents: the XR, each composed resource,
+// each result and each condition.
+func renderGolden(dir string) ([]byte, error) {
+	optional := func(name string) string {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			return ""
+		}
+		return path
+	}
+
+	cmd := &RenderCmd{
+		XR:             filepath.Join(dir, goldenXR),
+		Input:          filepath.Join(dir, goldenInput),
+		Offerings:      filepath.Join(dir, goldenOfferings),
+		Observed:       optional(goldenObserved),
+		ExtraResources: optional(goldenExtraResources),
+	}
+	req, err := cmd.request()
+	if err != nil {
+		return nil, err
+	}
+	cat, err := loadCatalog(cmd.Offerings)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := readObjects(cmd.ExtraResources)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := runFunction(context.Background(), &Function{log: logging.NewNopLogger(), ec2: cat.client, azure: cat}, req, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+	if err := writeDesired(out, req.GetObserved().GetComposite(), rsp.GetDesired()); err != nil {
+		return nil, err
+	}
+	for _, r := range rsp.GetResults() {
+		doc := map[string]any{
+			"apiVersion": "render.crossplane.io/v1beta1",
+			"kind":       "Result",
+			"severity":   r.GetSeverity().String(),
+			"message":    r.GetMessage(),
+		}
+		if err := writeYAML(out, doc); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range rsp.GetConditions() {
+		doc := map[string]any{
+			"apiVersion": "render.crossplane.io/v1beta1",
+			"kind":       "Condition",
+			"type":       c.GetType(),
+			"status":     c.GetStatus().String(),
+			"reason":     c.GetReason(),
+		}
+		if c.GetMessage() != "" {
+			doc["message"] = c.GetMessage()
+		}
+		if err := writeYAML(out, doc); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -5,6 +5,7 @@
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,4 +25,633 @@ type Input struct {
 
 	// Example is an example field. Replace it with whatever input you need. :)
 	Example string `json:"example"`
+
+	// Mode determines whether the Function composes the NodePool, or only
+	// audits it by reporting the NodePool it would compose.
+	// +kubebuilder:validation:Enum=Compose;Audit
+	// +kubebuilder:default=Compose
+	// +optional
+	Mode Mode `json:"mode,omitempty"`
+
+	// KarpenterAPIVersion is the version of the karpenter.sh API the composed
+	// NodePool uses. Use v1beta1 for clusters that run Karpenter releases
+	// older than v1.0.
+	// +kubebuilder:validation:Enum=v1;v1beta1
+	// +kubebuilder:default=v1
+	// +optional
+	KarpenterAPIVersion KarpenterAPIVersion `json:"karpenterAPIVersion,omitempty"`
+
+	// Diff configures how the Function reports changes it makes to an
+	// existing NodePool.
+	// +optional
+	Diff *Diff `json:"diff,omitempty"`
+
+	// Budget caps the NodePool limits so that a fully scaled out NodePool
+	// fits within a monthly spend ceiling.
+	// +optional
+	Budget *Budget `json:"budget,omitempty"`
+
+	// Policy is evaluated against the NodePool before it is composed. The
+	// Function returns a fatal result listing every violated rule instead of
+	// composing a NodePool that fails policy.
+	// +optional
+	Policy *Policy `json:"policy,omitempty"`
+
+	// Naming configures how the Function names the NodePool, or NodeGroup,
+	// it composes. The Function names it after the XR if this isn't set.
+	// +optional
+	Naming *Naming `json:"naming,omitempty"`
+
+	// Attribution configures the ownership and cost allocation labels the
+	// Function sets on the NodePool, or NodeGroup, it composes and on the
+	// nodes it launches. The Function sets no such labels if this isn't set.
+	// +optional
+	Attribution *Attribution `json:"attribution,omitempty"`
+
+	// Delivery configures how the Function delivers the NodePools it composes
+	// to remote workload clusters. The Function composes bare NodePools,
+	// which land in the control plane cluster, if this isn't set.
+	// +optional
+	Delivery *Delivery `json:"delivery,omitempty"`
+
+	// AutoMode configures the NodePools the Function composes for XRs whose
+	// spec.Autoscaler is eks-auto-mode. Their nodes use the built in default
+	// NodeClass if this isn't set.
+	// +optional
+	AutoMode *AutoMode `json:"autoMode,omitempty"`
+
+	// CapacityReservations makes the NodePools the Function composes for
+	// Karpenter on AWS launch nodes in On-Demand Capacity Reservations,
+	// falling back to on-demand capacity once the reservations are used up.
+	// +optional
+	CapacityReservations *CapacityReservations `json:"capacityReservations,omitempty"`
+
+	// NodeOverlays adjust the instance prices Karpenter uses when it schedules
+	// the NodePools the Function composes, e.g. to reflect negotiated
+	// discounts. The Function composes a Karpenter NodeOverlay for each.
+	// +optional
+	NodeOverlays []NodeOverlay `json:"nodeOverlays,omitempty"`
+
+	// Workload is the shape of the largest workload the NodePools the
+	// Function composes must run. The Function fails rather than compose a
+	// NodePool none of whose instance types can fit it.
+	// +optional
+	Workload *Workload `json:"workload,omitempty"`
+
+	// Shape constrains the instance types the NodePools the Function composes
+	// may launch, beyond their instance categories.
+	// +optional
+	Shape *InstanceShape `json:"shape,omitempty"`
+
+	// InstanceTypes allows and denies the instance types the NodePools the
+	// Function composes may launch by name pattern.
+	// +optional
+	InstanceTypes *InstanceTypeFilter `json:"instanceTypes,omitempty"`
+
+	// EnvironmentConfig configures the EnvironmentConfig the Function reads
+	// the XR's environment tier from. The tier's limits, instance categories,
+	// disruption settings and taints take precedence over the Function's
+	// built in tiers.
+	// +optional
+	EnvironmentConfig *EnvironmentConfigReference `json:"environmentConfig,omitempty"`
+
+	// NodeClassSelector selects the NodeClass the NodePool references from
+	// the existing NodeClasses, instead of the provider's default NodeClass.
+	// +optional
+	NodeClassSelector *NodeClassSelector `json:"nodeClassSelector,omitempty"`
+
+	// Context configures how the Function reads cluster metadata from, and
+	// writes the NodePool it decides on to, the pipeline Context. Cluster
+	// metadata in the Context takes precedence over the XR's fields. The
+	// Function doesn't use the Context unless this is set.
+	// +optional
+	Context *Context `json:"context,omitempty"`
+
+	// NodeGroup configures the EKS managed node group the Function composes
+	// instead of a NodePool for XRs whose spec.Autoscaler is
+	// cluster-autoscaler.
+	// +optional
+	NodeGroup *NodeGroup `json:"nodeGroup,omitempty"`
+
+	// ClusterAPI configures the Cluster API MachineDeployment and
+	// AWSMachineTemplate the Function composes instead of a NodePool for XRs
+	// whose spec.Autoscaler is cluster-api.
+	// +optional
+	ClusterAPI *ClusterAPI `json:"clusterAPI,omitempty"`
+}
+
+// A Mode determines what the Function does with the NodePool it computes.
+type Mode string
+
+// Modes.
+const (
+	// ModeCompose adds the NodePool to the desired composed resources.
+	ModeCompose Mode = "Compose"
+
+	// ModeAudit reports the NodePool, and how it differs from the observed
+	// NodePool, as results and XR status without composing it. Resources the
+	// XR already composes are kept as they are observed. Policy violations
+	// are reported as warnings rather than fatal results.
+	ModeAudit Mode = "Audit"
+)
+
+// A KarpenterAPIVersion is a version of the karpenter.sh API.
+type KarpenterAPIVersion string
+
+// Karpenter API versions.
+const (
+	// KarpenterV1 composes karpenter.sh/v1 NodePools.
+	KarpenterV1 KarpenterAPIVersion = "v1"
+
+	// KarpenterV1Beta1 composes karpenter.sh/v1beta1 NodePools.
+	KarpenterV1Beta1 KarpenterAPIVersion = "v1beta1"
+)
+
+// Diff configures how changes to an existing NodePool are reported.
+type Diff struct {
+	// WarnOnDrift reports changes to the NodePool's template as warnings
+	// rather than normal results. Karpenter replaces existing nodes when the
+	// template of their NodePool changes.
+	// +optional
+	WarnOnDrift bool `json:"warnOnDrift,omitempty"`
+}
+
+// Budget is a monthly spend ceiling for the NodePool of a single XR.
+type Budget struct {
+	// MaxMonthlySpend is the maximum monthly spend, in US dollars, of the
+	// NodePool composed for a single XR.
+	// +optional
+	MaxMonthlySpend *resource.Quantity `json:"maxMonthlySpend,omitempty"`
+
+	// Environments overrides MaxMonthlySpend for XRs whose spec.CxEnv matches
+	// the map key.
+	// +optional
+	Environments map[string]resource.Quantity `json:"environments,omitempty"`
+}
+
+// Policy is a set of guardrails a NodePool must satisfy.
+type Policy struct {
+	// Rules are CEL expressions that must evaluate to true for the NodePool
+	// to be composed. Each expression may refer to the variables nodePool
+	// (the NodePool, or NodeGroup, about to be composed), xr (the observed
+	// composite resource) and environment (the XR's spec.CxEnv).
+	// +optional
+	Rules []PolicyRule `json:"rules,omitempty"`
+
+	// ConfigMapRef references a ConfigMap holding additional rules. Each key
+	// of the ConfigMap's data is a rule name, and each value a CEL expression.
+	// +optional
+	ConfigMapRef *ConfigMapReference `json:"configMapRef,omitempty"`
+}
+
+// PolicyRule is a single policy guardrail.
+type PolicyRule struct {
+	// Name identifies the rule when it is violated.
+	Name string `json:"name"`
+
+	// Expression is a CEL expression that evaluates to true when the NodePool
+	// complies with the rule.
+	Expression string `json:"expression"`
+
+	// Message explains the rule when it is violated.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ConfigMapReference references a ConfigMap by name.
+type ConfigMapReference struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+}
+
+// NodeGroup configures an EKS managed node group scaled by Cluster Autoscaler.
+type NodeGroup struct {
+	// NodeRoleARN is the ARN of the IAM role the group's nodes assume.
+	NodeRoleARN string `json:"nodeRoleArn"`
+
+	// SubnetIDs are the subnets the group's nodes launch in.
+	// +kubebuilder:validation:MinItems=1
+	SubnetIDs []string `json:"subnetIds"`
+
+	// MinSize is the smallest number of nodes the group scales in to.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=1
+	// +optional
+	MinSize *int64 `json:"minSize,omitempty"`
+
+	// Labels applied to the group's nodes.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Taints applied to the group's nodes.
+	// +optional
+	Taints []Taint `json:"taints,omitempty"`
+}
+
+// ClusterAPI configures Cluster API machines, provisioned by the Cluster API
+// AWS provider.
+type ClusterAPI struct {
+	// Namespace of the workload cluster's Cluster API objects.
+	// +kubebuilder:default=default
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Version is the Kubernetes version machines run, e.g. v1.30.2.
+	Version string `json:"version"`
+
+	// BootstrapConfigTemplate is the name of the KubeadmConfigTemplate that
+	// bootstraps machines.
+	BootstrapConfigTemplate string `json:"bootstrapConfigTemplate"`
+
+	// IAMInstanceProfile is the IAM instance profile of machines.
+	// +optional
+	IAMInstanceProfile string `json:"iamInstanceProfile,omitempty"`
+
+	// SSHKeyName is the name of the EC2 key pair machines accept.
+	// +optional
+	SSHKeyName string `json:"sshKeyName,omitempty"`
+}
+
+// A Taint applied to the nodes of a node group.
+type Taint struct {
+	// Key of the taint.
+	Key string `json:"key"`
+
+	// Value of the taint.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// Effect of the taint, in EKS API format.
+	// +kubebuilder:validation:Enum=NO_SCHEDULE;NO_EXECUTE;PREFER_NO_SCHEDULE
+	Effect string `json:"effect"`
+}
+
+// Context configures how the Function uses the pipeline Context.
+type Context struct {
+	// Key of the Context value holding cluster metadata, as written by
+	// upstream functions like function-environment-configs.
+	// +kubebuilder:default="apiextensions.crossplane.io/environment"
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Fields are the paths of cluster metadata within the Context value.
+	// +optional
+	Fields ContextFields `json:"fields,omitempty"`
+
+	// SummaryKey is the Context key the Function writes a summary of the
+	// NodePool, or NodeGroup, it decides on to, for downstream functions.
+	// +kubebuilder:default="nodepools.fn.crossplane.io/summary"
+	// +optional
+	SummaryKey string `json:"summaryKey,omitempty"`
+}
+
+// ContextFields are the paths of cluster metadata within a Context value.
+type ContextFields struct {
+	// Region is the path of the cloud region nodes run in. It takes
+	// precedence over the XR's spec.AwsRegion or spec.AzureLocation.
+	// +kubebuilder:default=region
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// ClusterName is the path of the cluster's name. It takes precedence
+	// over the XR's spec.ClusterName.
+	// +kubebuilder:default=clusterName
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// KarpenterAPIVersion is the path of the karpenter.sh API version the
+	// cluster serves. It takes precedence over the Input's
+	// karpenterAPIVersion.
+	// +kubebuilder:default=karpenterAPIVersion
+	// +optional
+	KarpenterAPIVersion string `json:"karpenterAPIVersion,omitempty"`
+
+	// NodeRoleARN is the path of the ARN of the IAM role nodes assume. It
+	// takes precedence over the Input's nodeGroup.nodeRoleArn.
+	// +kubebuilder:default=nodeRoleArn
+	// +optional
+	NodeRoleARN string `json:"nodeRoleArn,omitempty"`
+}
+
+// NodeClassSelector selects one of the existing NodeClasses. Exactly one
+// NodeClass that matches the labels must be labelled with the XR's
+// environment and region.
+type NodeClassSelector struct {
+	// MatchLabels the candidate NodeClasses must have. All NodeClasses are
+	// candidates if it is empty.
+	// +optional
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// EnvironmentLabel is the label holding the environment, matched against
+	// the XR's spec.CxEnv, a NodeClass serves.
+	// +kubebuilder:default="nodepools.fn.crossplane.io/environment"
+	// +optional
+	EnvironmentLabel string `json:"environmentLabel,omitempty"`
+
+	// RegionLabel is the label holding the region a NodeClass serves.
+	// +kubebuilder:default="topology.kubernetes.io/region"
+	// +optional
+	RegionLabel string `json:"regionLabel,omitempty"`
+}
+
+// EnvironmentConfigReference references the EnvironmentConfig named after an
+// XR's spec.CxEnv.
+type EnvironmentConfigReference struct {
+	// NamePrefix is prepended to the XR's spec.CxEnv to form the name of the
+	// EnvironmentConfig.
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+
+	// APIVersion of the EnvironmentConfig.
+	// +kubebuilder:default="apiextensions.crossplane.io/v1beta1"
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Optional makes the Function fall back to its built in tiers if the
+	// EnvironmentConfig doesn't exist, rather than returning a fatal result.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Naming is a strategy for naming the cluster scoped NodePool, or NodeGroup,
+// the Function composes for an XR. Names longer than 63 characters are
+// truncated, and suffixed with a hash of the full name so they stay unique.
+type Naming struct {
+	// Prefix is a Go template prepended to the name. It may refer to .Name,
+	// .Namespace, .Environment, .Region and .Provider of the XR.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix is a Go template appended to the name. It may refer to the same
+	// fields as Prefix.
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+
+	// IncludeNamespace includes the namespace of the XR, or of its claim, in
+	// the name, so that namespaced XRs with the same name don't collide.
+	// +optional
+	IncludeNamespace bool `json:"includeNamespace,omitempty"`
+
+	// CheckExisting makes the Function refuse to compose a NodePool whose
+	// name is already taken by a NodePool that another composite resource,
+	// or nothing, owns.
+	// +optional
+	CheckExisting bool `json:"checkExisting,omitempty"`
+}
+
+// Attribution configures where the Function reads the team and cost center
+// an XR is attributed to. The XR's kind, name, namespace and environment are
+// always attributed.
+type Attribution struct {
+	// TeamField is the field path of the XR's team. Defaults to spec.Team.
+	// +optional
+	TeamField string `json:"teamField,omitempty"`
+
+	// CostCenterField is the field path of the XR's cost center. Defaults to
+	// spec.CostCenter.
+	// +optional
+	CostCenterField string `json:"costCenterField,omitempty"`
+}
+
+// Delivery wraps composed NodePools in provider-kubernetes Objects, which
+// create them in the workload cluster their ProviderConfig connects to.
+// NodeGroups are managed in the control plane cluster, and aren't wrapped.
+type Delivery struct {
+	// ProviderConfigField is the field path of the XR's provider-kubernetes
+	// ProviderConfig name. Defaults to spec.ProviderConfigName.
+	// +optional
+	ProviderConfigField string `json:"providerConfigField,omitempty"`
+
+	// ProviderConfigName is the name of the provider-kubernetes
+	// ProviderConfig used for XRs that don't set ProviderConfigField.
+	// +optional
+	ProviderConfigName string `json:"providerConfigName,omitempty"`
+}
+
+// AutoMode configures EKS Auto Mode NodePools.
+type AutoMode struct {
+	// NodeClass makes the Function compose a custom EKS Auto Mode NodeClass
+	// for each NodePool, named after it, instead of using the built in
+	// default NodeClass.
+	// +optional
+	NodeClass *AutoModeNodeClass `json:"nodeClass,omitempty"`
+}
+
+// AutoModeNodeClass configures an EKS Auto Mode NodeClass.
+type AutoModeNodeClass struct {
+	// Role is the name of the IAM role nodes use.
+	Role string `json:"role"`
+
+	// SubnetSelectorTerms select the subnets nodes are launched in.
+	// +optional
+	SubnetSelectorTerms []SelectorTerm `json:"subnetSelectorTerms,omitempty"`
+
+	// SecurityGroupSelectorTerms select the security groups of nodes.
+	// +optional
+	SecurityGroupSelectorTerms []SelectorTerm `json:"securityGroupSelectorTerms,omitempty"`
+
+	// Tags are applied to the EC2 instances nodes run on.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// SelectorTerm selects AWS resources by ID or by tags.
+type SelectorTerm struct {
+	// ID of the resource.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// Tags the resource has.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// CapacityReservations configures the On-Demand Capacity Reservations (ODCRs)
+// NodePools launch nodes in. The Function checks active reservations match
+// the selector terms in the XR's region, and composes an EC2NodeClass that
+// selects them.
+type CapacityReservations struct {
+	// SelectorTerms select the capacity reservations nodes may use. A
+	// reservation is selected if it matches any term.
+	// +kubebuilder:validation:MinItems=1
+	SelectorTerms []CapacityReservationSelectorTerm `json:"selectorTerms"`
+
+	// Environments are the values of spec.CxEnv whose XRs use capacity
+	// reservations. XRs of every environment use them if this is empty.
+	// +optional
+	Environments []string `json:"environments,omitempty"`
+
+	// NodeClass configures the EC2NodeClass the Function composes for each
+	// NodePool, named after it.
+	NodeClass EC2NodeClass `json:"nodeClass"`
+}
+
+// CapacityReservationSelectorTerm selects capacity reservations by ID, or by
+// tags and owning account.
+type CapacityReservationSelectorTerm struct {
+	// ID of the capacity reservation.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// OwnerID is the ID of the AWS account that owns the capacity reservation.
+	// +optional
+	OwnerID string `json:"ownerID,omitempty"`
+
+	// Tags the capacity reservation has.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// EC2NodeClass configures a Karpenter EC2NodeClass.
+type EC2NodeClass struct {
+	// Role is the name of the IAM role nodes use.
+	Role string `json:"role"`
+
+	// AMIAlias selects the AMI family and version nodes run.
+	// +kubebuilder:default="al2023@latest"
+	// +optional
+	AMIAlias string `json:"amiAlias,omitempty"`
+
+	// SubnetSelectorTerms select the subnets nodes are launched in.
+	// +optional
+	SubnetSelectorTerms []SelectorTerm `json:"subnetSelectorTerms,omitempty"`
+
+	// SecurityGroupSelectorTerms select the security groups of nodes.
+	// +optional
+	SecurityGroupSelectorTerms []SelectorTerm `json:"securityGroupSelectorTerms,omitempty"`
+
+	// Tags are applied to the EC2 instances nodes run on.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// A NodeOverlay adjusts the price of some of a NodePool's instance types.
+// Exactly one of PriceAdjustment and Price must be set.
+type NodeOverlay struct {
+	// Name identifies the overlay. The composed NodeOverlay is named after the
+	// NodePool and this name.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Families are the instance families, e.g. m6i, whose price is adjusted.
+	// The price of every family is adjusted if this is empty.
+	// +optional
+	Families []string `json:"families,omitempty"`
+
+	// CapacityTypes are the capacity types whose price is adjusted. The price
+	// of every capacity type is adjusted if this is empty.
+	// +optional
+	CapacityTypes []CapacityType `json:"capacityTypes,omitempty"`
+
+	// PriceAdjustment changes the price by an amount in US dollars, e.g.
+	// -0.02, or a percentage, e.g. -15%.
+	// +kubebuilder:validation:Pattern=`^[+-]\d*\.?\d+%?$`
+	// +optional
+	PriceAdjustment string `json:"priceAdjustment,omitempty"`
+
+	// Price replaces the hourly price, in US dollars.
+	// +kubebuilder:validation:Pattern=`^\d*\.?\d+$`
+	// +optional
+	Price string `json:"price,omitempty"`
+
+	// Weight orders overlapping overlays. Karpenter applies the overlay with
+	// the highest weight.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// A CapacityType is a kind of EC2 capacity.
+// +kubebuilder:validation:Enum=on-demand;spot;reserved
+type CapacityType string
+
+// A Workload is what a single node must have room for, after the resources
+// Karpenter reserves for the system.
+type Workload struct {
+	// CPU the workload requests.
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+
+	// Memory the workload requests.
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// GPUs the workload requests.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	GPUs int64 `json:"gpus,omitempty"`
+
+	// EphemeralStorage the workload requests. Nodes are assumed to have
+	// Karpenter's default 20Gi root volume.
+	// +optional
+	EphemeralStorage *resource.Quantity `json:"ephemeralStorage,omitempty"`
+
+	// PodsPerNode is how many pods, including DaemonSet pods, a node must be
+	// able to run.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PodsPerNode int64 `json:"podsPerNode,omitempty"`
+}
+
+// An InstanceShape constrains the vCPUs, memory, generation and size of
+// instance types. Karpenter can't constrain memory per vCPU directly, so each
+// memory per vCPU bound requires the vCPU bound it's multiplied by to bound
+// instance memory.
+type InstanceShape struct {
+	// MinVCPUs is the fewest vCPUs an instance type may have.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinVCPUs *int64 `json:"minVCPUs,omitempty"`
+
+	// MaxVCPUs is the most vCPUs an instance type may have.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxVCPUs *int64 `json:"maxVCPUs,omitempty"`
+
+	// MinMemoryPerVCPU bounds instance memory to at least MinVCPUs times this
+	// much. It requires MinVCPUs.
+	// +optional
+	MinMemoryPerVCPU *resource.Quantity `json:"minMemoryPerVCPU,omitempty"`
+
+	// MaxMemoryPerVCPU bounds instance memory to at most MaxVCPUs times this
+	// much. It requires MaxVCPUs.
+	// +optional
+	MaxMemoryPerVCPU *resource.Quantity `json:"maxMemoryPerVCPU,omitempty"`
+
+	// MinGeneration is the oldest instance generation, e.g. 6 for m6i, an
+	// instance type may be.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinGeneration *int64 `json:"minGeneration,omitempty"`
+
+	// ExcludedSizes are instance sizes, e.g. metal, an instance type may not
+	// be.
+	// +optional
+	ExcludedSizes []string `json:"excludedSizes,omitempty"`
+}
+
+// An InstanceTypeFilter allows and denies instance types by name pattern.
+type InstanceTypeFilter struct {
+	InstanceTypePatterns `json:",inline"`
+
+	// Environments adds patterns for XRs whose spec.CxEnv matches the map
+	// key, e.g. to deny burstable instance types in production.
+	// +optional
+	Environments map[string]InstanceTypePatterns `json:"environments,omitempty"`
+}
+
+// InstanceTypePatterns are instance type name patterns, e.g. t* or *.metal.
+// A pattern matches a whole name; * matches any run of characters, ? any one
+// character and [...] any one of a class of characters.
+type InstanceTypePatterns struct {
+	// Allow allows only the instance types that match any of these patterns.
+	// Every instance type is allowed if this is empty.
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny denies the instance types that match any of these patterns, even
+	// if Allow allows them.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
 }
@@ -5,14 +5,392 @@
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Attribution) DeepCopyInto(out *Attribution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Attribution.
+func (in *Attribution) DeepCopy() *Attribution {
+	if in == nil {
+		return nil
+	}
+	out := new(Attribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoMode) DeepCopyInto(out *AutoMode) {
+	*out = *in
+	if in.NodeClass != nil {
+		in, out := &in.NodeClass, &out.NodeClass
+		*out = new(AutoModeNodeClass)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoMode.
+func (in *AutoMode) DeepCopy() *AutoMode {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoMode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoModeNodeClass) DeepCopyInto(out *AutoModeNodeClass) {
+	*out = *in
+	if in.SubnetSelectorTerms != nil {
+		in, out := &in.SubnetSelectorTerms, &out.SubnetSelectorTerms
+		*out = make([]SelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecurityGroupSelectorTerms != nil {
+		in, out := &in.SecurityGroupSelectorTerms, &out.SecurityGroupSelectorTerms
+		*out = make([]SelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoModeNodeClass.
+func (in *AutoModeNodeClass) DeepCopy() *AutoModeNodeClass {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoModeNodeClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Budget) DeepCopyInto(out *Budget) {
+	*out = *in
+	if in.MaxMonthlySpend != nil {
+		in, out := &in.MaxMonthlySpend, &out.MaxMonthlySpend
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make(map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Budget.
+func (in *Budget) DeepCopy() *Budget {
+	if in == nil {
+		return nil
+	}
+	out := new(Budget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservationSelectorTerm) DeepCopyInto(out *CapacityReservationSelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservationSelectorTerm.
+func (in *CapacityReservationSelectorTerm) DeepCopy() *CapacityReservationSelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservationSelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityReservations) DeepCopyInto(out *CapacityReservations) {
+	*out = *in
+	if in.SelectorTerms != nil {
+		in, out := &in.SelectorTerms, &out.SelectorTerms
+		*out = make([]CapacityReservationSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.NodeClass.DeepCopyInto(&out.NodeClass)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservations.
+func (in *CapacityReservations) DeepCopy() *CapacityReservations {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityReservations)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterAPI) DeepCopyInto(out *ClusterAPI) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAPI.
+func (in *ClusterAPI) DeepCopy() *ClusterAPI {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAPI)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Context) DeepCopyInto(out *Context) {
+	*out = *in
+	out.Fields = in.Fields
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Context.
+func (in *Context) DeepCopy() *Context {
+	if in == nil {
+		return nil
+	}
+	out := new(Context)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContextFields) DeepCopyInto(out *ContextFields) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContextFields.
+func (in *ContextFields) DeepCopy() *ContextFields {
+	if in == nil {
+		return nil
+	}
+	out := new(ContextFields)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Delivery) DeepCopyInto(out *Delivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Delivery.
+func (in *Delivery) DeepCopy() *Delivery {
+	if in == nil {
+		return nil
+	}
+	out := new(Delivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Diff) DeepCopyInto(out *Diff) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Diff.
+func (in *Diff) DeepCopy() *Diff {
+	if in == nil {
+		return nil
+	}
+	out := new(Diff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EC2NodeClass) DeepCopyInto(out *EC2NodeClass) {
+	*out = *in
+	if in.SubnetSelectorTerms != nil {
+		in, out := &in.SubnetSelectorTerms, &out.SubnetSelectorTerms
+		*out = make([]SelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecurityGroupSelectorTerms != nil {
+		in, out := &in.SecurityGroupSelectorTerms, &out.SecurityGroupSelectorTerms
+		*out = make([]SelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EC2NodeClass.
+func (in *EC2NodeClass) DeepCopy() *EC2NodeClass {
+	if in == nil {
+		return nil
+	}
+	out := new(EC2NodeClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvironmentConfigReference) DeepCopyInto(out *EnvironmentConfigReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvironmentConfigReference.
+func (in *EnvironmentConfigReference) DeepCopy() *EnvironmentConfigReference {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvironmentConfigReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Input) DeepCopyInto(out *Input) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Diff != nil {
+		in, out := &in.Diff, &out.Diff
+		*out = new(Diff)
+		**out = **in
+	}
+	if in.Budget != nil {
+		in, out := &in.Budget, &out.Budget
+		*out = new(Budget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(Policy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Naming != nil {
+		in, out := &in.Naming, &out.Naming
+		*out = new(Naming)
+		**out = **in
+	}
+	if in.Attribution != nil {
+		in, out := &in.Attribution, &out.Attribution
+		*out = new(Attribution)
+		**out = **in
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(Delivery)
+		**out = **in
+	}
+	if in.AutoMode != nil {
+		in, out := &in.AutoMode, &out.AutoMode
+		*out = new(AutoMode)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacityReservations != nil {
+		in, out := &in.CapacityReservations, &out.CapacityReservations
+		*out = new(CapacityReservations)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeOverlays != nil {
+		in, out := &in.NodeOverlays, &out.NodeOverlays
+		*out = make([]NodeOverlay, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Workload != nil {
+		in, out := &in.Workload, &out.Workload
+		*out = new(Workload)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Shape != nil {
+		in, out := &in.Shape, &out.Shape
+		*out = new(InstanceShape)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InstanceTypes != nil {
+		in, out := &in.InstanceTypes, &out.InstanceTypes
+		*out = new(InstanceTypeFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EnvironmentConfig != nil {
+		in, out := &in.EnvironmentConfig, &out.EnvironmentConfig
+		*out = new(EnvironmentConfigReference)
+		**out = **in
+	}
+	if in.NodeClassSelector != nil {
+		in, out := &in.NodeClassSelector, &out.NodeClassSelector
+		*out = new(NodeClassSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Context != nil {
+		in, out := &in.Context, &out.Context
+		*out = new(Context)
+		**out = **in
+	}
+	if in.NodeGroup != nil {
+		in, out := &in.NodeGroup, &out.NodeGroup
+		*out = new(NodeGroup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterAPI != nil {
+		in, out := &in.ClusterAPI, &out.ClusterAPI
+		*out = new(ClusterAPI)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Input.
@@ -32,3 +410,307 @@ func (in *Input) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceShape) DeepCopyInto(out *InstanceShape) {
+	*out = *in
+	if in.MinVCPUs != nil {
+		in, out := &in.MinVCPUs, &out.MinVCPUs
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxVCPUs != nil {
+		in, out := &in.MaxVCPUs, &out.MaxVCPUs
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MinMemoryPerVCPU != nil {
+		in, out := &in.MinMemoryPerVCPU, &out.MinMemoryPerVCPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxMemoryPerVCPU != nil {
+		in, out := &in.MaxMemoryPerVCPU, &out.MaxMemoryPerVCPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinGeneration != nil {
+		in, out := &in.MinGeneration, &out.MinGeneration
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ExcludedSizes != nil {
+		in, out := &in.ExcludedSizes, &out.ExcludedSizes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceShape.
+func (in *InstanceShape) DeepCopy() *InstanceShape {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceShape)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceTypeFilter) DeepCopyInto(out *InstanceTypeFilter) {
+	*out = *in
+	in.InstanceTypePatterns.DeepCopyInto(&out.InstanceTypePatterns)
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make(map[string]InstanceTypePatterns, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceTypeFilter.
+func (in *InstanceTypeFilter) DeepCopy() *InstanceTypeFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceTypeFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceTypePatterns) DeepCopyInto(out *InstanceTypePatterns) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceTypePatterns.
+func (in *InstanceTypePatterns) DeepCopy() *InstanceTypePatterns {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceTypePatterns)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Naming) DeepCopyInto(out *Naming) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Naming.
+func (in *Naming) DeepCopy() *Naming {
+	if in == nil {
+		return nil
+	}
+	out := new(Naming)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeClassSelector) DeepCopyInto(out *NodeClassSelector) {
+	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeClassSelector.
+func (in *NodeClassSelector) DeepCopy() *NodeClassSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeClassSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroup) DeepCopyInto(out *NodeGroup) {
+	*out = *in
+	if in.SubnetIDs != nil {
+		in, out := &in.SubnetIDs, &out.SubnetIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinSize != nil {
+		in, out := &in.MinSize, &out.MinSize
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Taints != nil {
+		in, out := &in.Taints, &out.Taints
+		*out = make([]Taint, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroup.
+func (in *NodeGroup) DeepCopy() *NodeGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeOverlay) DeepCopyInto(out *NodeOverlay) {
+	*out = *in
+	if in.Families != nil {
+		in, out := &in.Families, &out.Families
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CapacityTypes != nil {
+		in, out := &in.CapacityTypes, &out.CapacityTypes
+		*out = make([]CapacityType, len(*in))
+		copy(*out, *in)
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeOverlay.
+func (in *NodeOverlay) DeepCopy() *NodeOverlay {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeOverlay)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Policy) DeepCopyInto(out *Policy) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PolicyRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ConfigMapReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Policy.
+func (in *Policy) DeepCopy() *Policy {
+	if in == nil {
+		return nil
+	}
+	out := new(Policy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRule) DeepCopyInto(out *PolicyRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRule.
+func (in *PolicyRule) DeepCopy() *PolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelectorTerm) DeepCopyInto(out *SelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelectorTerm.
+func (in *SelectorTerm) DeepCopy() *SelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(SelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Taint) DeepCopyInto(out *Taint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Taint.
+func (in *Taint) DeepCopy() *Taint {
+	if in == nil {
+		return nil
+	}
+	out := new(Taint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Workload) DeepCopyInto(out *Workload) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.EphemeralStorage != nil {
+		in, out := &in.EphemeralStorage, &out.EphemeralStorage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Workload.
+func (in *Workload) DeepCopy() *Workload {
+	if in == nil {
+		return nil
+	}
+	out := new(Workload)
+	in.DeepCopyInto(out)
+	return out
+}
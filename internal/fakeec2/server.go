@@ -0,0 +1,525 @@
+// Package fakeec2 implements a stand-in for the parts of the EC2 API the
+// Function uses. It answers from fixture data, so the Function's AWS code path
+// can be tested without network access or AWS credentials.
+package fakeec2
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// xmlns is the namespace of EC2 API responses.
+const xmlns = "http://ec2.amazonaws.com/doc/2016-11-15/"
+
+// defaultMaxResults is how many items the server returns per page when a
+// request doesn't set MaxResults.
+const defaultMaxResults = 1000
+
+// credentialRegion extracts the region from the credential scope of a SigV4
+// Authorization header.
+var credentialRegion = regexp.MustCompile(`Credential=[^/]+/[^/]+/([^/]+)/ec2/aws4_request`)
+
+// A Fixture is the data the server answers with.
+type Fixture struct {
+	// Regions maps an AWS region to what is offered in it.
+	Regions map[string]Region `json:"regions"`
+
+	// InstanceTypes describes instance types, by name.
+	InstanceTypes map[string]InstanceType `json:"instanceTypes,omitempty"`
+}
+
+// A Region is what AWS offers in a region.
+type Region struct {
+	// InstanceTypes offered in the region.
+	InstanceTypes []string `json:"instanceTypes"`
+
+	// SpotPrices maps an instance type to its hourly spot price in US dollars.
+	SpotPrices map[string]string `json:"spotPrices,omitempty"`
+
+	// CapacityReservations in the region.
+	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty"`
+}
+
+// A CapacityReservation is an On-Demand Capacity Reservation.
+type CapacityReservation struct {
+	ID                 string            `json:"id"`
+	OwnerID            string            `json:"ownerID,omitempty"`
+	InstanceType       string            `json:"instanceType"`
+	AvailabilityZone   string            `json:"availabilityZone"`
+	TotalInstances     int32             `json:"totalInstances"`
+	AvailableInstances int32             `json:"availableInstances"`
+	State              string            `json:"state,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+}
+
+// An InstanceType describes an instance type.
+type InstanceType struct {
+	VCPUs                     int32 `json:"vcpus"`
+	MemoryMiB                 int64 `json:"memoryMiB"`
+	GPUs                      int32 `json:"gpus,omitempty"`
+	MaxNetworkInterfaces      int32 `json:"maxNetworkInterfaces,omitempty"`
+	IPv4AddressesPerInterface int32 `json:"ipv4AddressesPerInterface,omitempty"`
+}
+
+// LoadFixture loads a Fixture from a YAML or JSON file.
+func LoadFixture(path string) (*Fixture, error) {
+	b, err := os.ReadFile(path) //nolint:gosec // Reading a user supplied file is intended.
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read fixture %q", path)
+	}
+	f := &Fixture{}
+	if err := yaml.UnmarshalStrict(b, f); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse fixture %q", path)
+	}
+	return f, nil
+}
+
+// A Fault is an error the server returns instead of answering a request.
+type Fault struct {
+	// Status is the HTTP status code of the error response.
+	Status int
+
+	// Code and Message of the EC2 error, e.g. RequestLimitExceeded.
+	Code    string
+	Message string
+}
+
+// A Server answers EC2 API requests from a Fixture.
+type Server struct {
+	fixture *Fixture
+
+	mu       sync.Mutex
+	faults   []Fault
+	requests []string
+}
+
+// An Option configures a Server.
+type Option func(s *Server)
+
+// WithFaults makes the server answer its first requests with the supplied
+// faults, in order, before answering from its fixture.
+func WithFaults(f ...Fault) Option {
+	return func(s *Server) {
+		s.faults = append(s.faults, f...)
+	}
+}
+
+// New returns a Server that answers from the supplied fixture.
+func New(f *Fixture, o ...Option) *Server {
+	s := &Server{fixture: f}
+	for _, fn := range o {
+		fn(s)
+	}
+	return s
+}
+
+// Requests returns the Action of every request the server has received, in
+// order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP answers an EC2 query API request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, Fault{Status: http.StatusBadRequest, Code: "MalformedQueryString", Message: err.Error()})
+		return
+	}
+	action := r.Form.Get("Action")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, action)
+	var fault *Fault
+	if len(s.faults) > 0 {
+		fault = &s.faults[0]
+		s.faults = s.faults[1:]
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		writeError(w, *fault)
+		return
+	}
+
+	region := ""
+	if m := credentialRegion.FindStringSubmatch(r.Header.Get("Authorization")); m != nil {
+		region = m[1]
+	}
+	if _, ok := s.fixture.Regions[region]; !ok {
+		writeError(w, Fault{Status: http.StatusUnauthorized, Code: "AuthFailure", Message: fmt.Sprintf("region %q is not enabled for this account", region)})
+		return
+	}
+
+	switch action {
+	case "DescribeInstanceTypeOfferings":
+		s.describeInstanceTypeOfferings(w, r, region)
+	case "DescribeInstanceTypes":
+		s.describeInstanceTypes(w, r)
+	case "DescribeSpotPriceHistory":
+		s.describeSpotPriceHistory(w, r, region)
+	case "DescribeCapacityReservations":
+		s.describeCapacityReservations(w, r, region)
+	case "DescribeRegions":
+		s.describeRegions(w, r)
+	default:
+		writeError(w, Fault{Status: http.StatusBadRequest, Code: "InvalidAction", Message: fmt.Sprintf("the action %s is not valid for this web service", action)})
+	}
+}
+
+type offering struct {
+	InstanceType string `xml:"instanceType"`
+	LocationType string `xml:"locationType"`
+	Location     string `xml:"location"`
+}
+
+type describeInstanceTypeOfferingsResponse struct {
+	XMLName   xml.Name   `xml:"DescribeInstanceTypeOfferingsResponse"`
+	Xmlns     string     `xml:"xmlns,attr"`
+	RequestID string     `xml:"requestId"`
+	Offerings []offering `xml:"instanceTypeOfferingSet>item"`
+	NextToken string     `xml:"nextToken,omitempty"`
+}
+
+func (s *Server) describeInstanceTypeOfferings(w http.ResponseWriter, r *http.Request, region string) {
+	if lt := r.Form.Get("LocationType"); lt != "" && lt != "region" {
+		writeError(w, Fault{Status: http.StatusBadRequest, Code: "InvalidParameterValue", Message: fmt.Sprintf("the fake EC2 API only supports location type region, not %s", lt)})
+		return
+	}
+	types := filter(s.fixture.Regions[region].InstanceTypes, filterValues(r, "instance-type"))
+	page, next, err := paginate(r, types)
+	if err != nil {
+		writeError(w, Fault{Status: http.StatusBadRequest, Code: "InvalidParameterValue", Message: err.Error()})
+		return
+	}
+
+	rsp := describeInstanceTypeOfferingsResponse{Xmlns: xmlns, RequestID: requestID(r), NextToken: next}
+	for _, t := range page {
+		rsp.Offerings = append(rsp.Offerings, offering{InstanceType: t, LocationType: "region", Location: region})
+	}
+	writeXML(w, rsp)
+}
+
+type gpu struct {
+	Count int32 `xml:"count"`
+}
+
+type instanceTypeInfo struct {
+	InstanceType              string `xml:"instanceType"`
+	VCPUs                     int32  `xml:"vCpuInfo>defaultVCpus"`
+	MemoryMiB                 int64  `xml:"memoryInfo>sizeInMiB"`
+	GPUs                      []gpu  `xml:"gpuInfo>gpus>item,omitempty"`
+	MaxNetworkInterfaces      int32  `xml:"networkInfo>maximumNetworkInterfaces,omitempty"`
+	IPv4AddressesPerInterface int32  `xml:"networkInfo>ipv4AddressesPerInterface,omitempty"`
+}
+
+type describeInstanceTypesResponse struct {
+	XMLName       xml.Name           `xml:"DescribeInstanceTypesResponse"`
+	Xmlns         string             `xml:"xmlns,attr"`
+	RequestID     string             `xml:"requestId"`
+	InstanceTypes []instanceTypeInfo `xml:"instanceTypeSet>item"`
+	NextToken     string             `xml:"nextToken,omitempty"`
+}
+
+func (s *Server) describeInstanceTypes(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.fixture.InstanceTypes))
+	for name := range s.fixture.InstanceTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if requested := listValues(r, "InstanceType"); len(requested) > 0 {
+		for _, t := range requested {
+			if _, ok := s.fixture.InstanceTypes[t]; !ok {
+				writeError(w, Fault{Status: http.StatusBadRequest, Code: "InvalidInstanceType", Message: fmt.Sprintf("the following supplied instance types do not exist: [%s]", t)})
+				return
+			}
+		}
+		names = requested
+	}
+	names = filter(names, filterValues(r, "instance-type"))
+
+	page, next, err := paginate(r, names)
+	if err != nil {
+		writeError(w, Fault{Status: http.StatusBadRequest, Code: "InvalidParameterValue", Message: err.Error()})
+		return
+	}
+
+	rsp := describeInstanceTypesResponse{Xmlns: xmlns, RequestID: requestID(r), NextToken: next}
+	for _, name := range page {
+		it := s.fixture.InstanceTypes[name]
+		info := instanceTypeInfo{
+			InstanceType:              name,
+			VCPUs:                     it.VCPUs,
+			MemoryMiB:                 it.MemoryMiB,
+			MaxNetworkInterfaces:      it.MaxNetworkInterfaces,
+			IPv4AddressesPerInterface: it.IPv4AddressesPerInterface,
+		}
+		if it.GPUs > 0 {
+			info.GPUs = []gpu{{Count: it.GPUs}}
+		}
+		rsp.InstanceTypes = append(rsp.InstanceTypes, info)
+	}
+	writeXML(w, rsp)
+}
+
+type spotPrice struct {
+	AvailabilityZone   string `xml:"availabilityZone"`
+	InstanceType       string `xml:"instanceType"`
+	ProductDescription string `xml:"productDescription"`
+	SpotPrice          string `xml:"spotPrice"`
+	Timestamp          string `xml:"timestamp"`
+}
+
+type describeSpotPriceHistoryResponse struct {
+	XMLName   xml.Name    `xml:"DescribeSpotPriceHistoryResponse"`
+	Xmlns     string      `xml:"xmlns,attr"`
+	RequestID string      `xml:"requestId"`
+	Prices    []spotPrice `xml:"spotPriceHistorySet>item"`
+	NextToken string      `xml:"nextToken"`
+}
+
+func (s *Server) describeSpotPriceHistory(w http.ResponseWriter, r *http.Request, region string) {
+	prices := s.fixture.Regions[region].SpotPrices
+	names := make([]string, 0, len(prices))
+	for name := range prices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if requested := listValues(r, "InstanceType"); len(requested) > 0 {
+		names = filter(names, requested)
+	}
+
+	page, next, err := paginate(r, names)
+	if err != nil {
+		writeError(w, Fault{Status: http.StatusBadRequest, Code: "InvalidParameterValue", Message: err.Error()})
+		return
+	}
+
+	rsp := describeSpotPriceHistoryResponse{Xmlns: xmlns, RequestID: requestID(r), NextToken: next}
+	for _, name := range page {
+		rsp.Prices = append(rsp.Prices, spotPrice{
+			AvailabilityZone:   region + "a",
+			InstanceType:       name,
+			ProductDescription: "Linux/UNIX",
+			SpotPrice:          prices[name],
+			Timestamp:          "2024-01-01T00:00:00.000Z",
+		})
+	}
+	writeXML(w, rsp)
+}
+
+type tag struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+type capacityReservation struct {
+	ID                 string `xml:"capacityReservationId"`
+	OwnerID            string `xml:"ownerId,omitempty"`
+	InstanceType       string `xml:"instanceType"`
+	AvailabilityZone   string `xml:"availabilityZone"`
+	TotalInstances     int32  `xml:"totalInstanceCount"`
+	AvailableInstances int32  `xml:"availableInstanceCount"`
+	State              string `xml:"state"`
+	Tags               []tag  `xml:"tagSet>item"`
+}
+
+type describeCapacityReservationsResponse struct {
+	XMLName      xml.Name              `xml:"DescribeCapacityReservationsResponse"`
+	Xmlns        string                `xml:"xmlns,attr"`
+	RequestID    string                `xml:"requestId"`
+	Reservations []capacityReservation `xml:"capacityReservationSet>item"`
+	NextToken    string                `xml:"nextToken,omitempty"`
+}
+
+// describeCapacityReservations answers with the region's capacity
+// reservations. Reservations without a state are active.
+func (s *Server) describeCapacityReservations(w http.ResponseWriter, r *http.Request, region string) {
+	byID := map[string]CapacityReservation{}
+	ids := []string{}
+	states := filterValues(r, "state")
+	for _, cr := range s.fixture.Regions[region].CapacityReservations {
+		if cr.State == "" {
+			cr.State = "active"
+		}
+		if len(states) > 0 && len(filter([]string{cr.State}, states)) == 0 {
+			continue
+		}
+		byID[cr.ID] = cr
+		ids = append(ids, cr.ID)
+	}
+	sort.Strings(ids)
+	if requested := listValues(r, "CapacityReservationId"); len(requested) > 0 {
+		ids = filter(ids, requested)
+	}
+
+	page, next, err := paginate(r, ids)
+	if err != nil {
+		writeError(w, Fault{Status: http.StatusBadRequest, Code: "InvalidParameterValue", Message: err.Error()})
+		return
+	}
+
+	rsp := describeCapacityReservationsResponse{Xmlns: xmlns, RequestID: requestID(r), NextToken: next}
+	for _, id := range page {
+		cr := byID[id]
+		out := capacityReservation{
+			ID:                 cr.ID,
+			OwnerID:            cr.OwnerID,
+			InstanceType:       cr.InstanceType,
+			AvailabilityZone:   cr.AvailabilityZone,
+			TotalInstances:     cr.TotalInstances,
+			AvailableInstances: cr.AvailableInstances,
+			State:              cr.State,
+		}
+		keys := make([]string, 0, len(cr.Tags))
+		for k := range cr.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out.Tags = append(out.Tags, tag{Key: k, Value: cr.Tags[k]})
+		}
+		rsp.Reservations = append(rsp.Reservations, out)
+	}
+	writeXML(w, rsp)
+}
+
+type regionInfo struct {
+	RegionName     string `xml:"regionName"`
+	RegionEndpoint string `xml:"regionEndpoint"`
+	OptInStatus    string `xml:"optInStatus"`
+}
+
+type describeRegionsResponse struct {
+	XMLName   xml.Name     `xml:"DescribeRegionsResponse"`
+	Xmlns     string       `xml:"xmlns,attr"`
+	RequestID string       `xml:"requestId"`
+	Regions   []regionInfo `xml:"regionInfo>item"`
+}
+
+// describeRegions answers with the fixture's regions, ordered by name.
+func (s *Server) describeRegions(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.fixture.Regions))
+	for name := range s.fixture.Regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if requested := listValues(r, "RegionName"); len(requested) > 0 {
+		names = filter(names, requested)
+	}
+
+	rsp := describeRegionsResponse{Xmlns: xmlns, RequestID: requestID(r)}
+	for _, name := range names {
+		rsp.Regions = append(rsp.Regions, regionInfo{RegionName: name, RegionEndpoint: "ec2." + name + ".amazonaws.com", OptInStatus: "opt-in-not-required"})
+	}
+	writeXML(w, rsp)
+}
+
+// filterValues returns the values of the named request filter, e.g. the
+// values of Filter.1.Value.N where Filter.1.Name is instance-type.
+func filterValues(r *http.Request, name string) []string {
+	for i := 1; ; i++ {
+		n := r.Form.Get(fmt.Sprintf("Filter.%d.Name", i))
+		if n == "" {
+			return nil
+		}
+		if n == name {
+			return listValues(r, fmt.Sprintf("Filter.%d.Value", i))
+		}
+	}
+}
+
+// listValues returns the values of a flattened list parameter, e.g.
+// InstanceType.1, InstanceType.2 and so on.
+func listValues(r *http.Request, prefix string) []string {
+	var values []string
+	for i := 1; ; i++ {
+		v := r.Form.Get(prefix + "." + strconv.Itoa(i))
+		if v == "" {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// filter returns the values that match any of the supplied patterns. A
+// pattern may end in * to match any suffix. Every value matches if there are
+// no patterns.
+func filter(values, patterns []string) []string {
+	if len(patterns) == 0 {
+		return values
+	}
+	var out []string
+	for _, v := range values {
+		for _, p := range patterns {
+			if v == p || (strings.HasSuffix(p, "*") && strings.HasPrefix(v, strings.TrimSuffix(p, "*"))) {
+				out = append(out, v)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// paginate returns the page of items requested by the MaxResults and
+// NextToken parameters of the request, and the token of the next page. The
+// token of the last page is empty.
+func paginate(r *http.Request, items []string) ([]string, string, error) {
+	limit := defaultMaxResults
+	if v := r.Form.Get("MaxResults"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, "", errors.Errorf("invalid MaxResults %q", v)
+		}
+		limit = n
+	}
+	start := 0
+	if v := r.Form.Get("NextToken"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > len(items) {
+			return nil, "", errors.Errorf("invalid NextToken %q", v)
+		}
+		start = n
+	}
+	end := min(start+limit, len(items))
+	next := ""
+	if end < len(items) {
+		next = strconv.Itoa(end)
+	}
+	return items[start:end], next, nil
+}
+
+func requestID(r *http.Request) string {
+	return fmt.Sprintf("fake-%s-%s", r.Form.Get("Action"), r.Form.Get("NextToken"))
+}
+
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Response"`
+	Code      string   `xml:"Errors>Error>Code"`
+	Message   string   `xml:"Errors>Error>Message"`
+	RequestID string   `xml:"RequestID"`
+}
+
+func writeError(w http.ResponseWriter, f Fault) {
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.WriteHeader(f.Status)
+	_ = xml.NewEncoder(w).Encode(errorResponse{Code: f.Code, Message: f.Message, RequestID: "fake-error"})
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,268 @@
+package fakeec2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// client returns an EC2 client for region that calls the supplied server.
+func client(t *testing.T, srv *httptest.Server, region string) *ec2.Client {
+	t.Helper()
+	return ec2.New(ec2.Options{
+		Region:       region,
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+	})
+}
+
+func fixture(t *testing.T) *Fixture {
+	t.Helper()
+	f, err := LoadFixture("testdata/fixture.yaml")
+	if err != nil {
+		t.Fatalf("LoadFixture(...): %v", err)
+	}
+	return f
+}
+
+func TestDescribeInstanceTypeOfferings(t *testing.T) {
+	type want struct {
+		types    []string
+		requests int
+	}
+
+	cases := map[string]struct {
+		reason string
+		region string
+		params *ec2.DescribeInstanceTypeOfferingsInput
+		want   want
+	}{
+		"AllPages": {
+			reason: "The paginator should return every instance type offered in the region, one page at a time.",
+			region: "us-east-1",
+			params: &ec2.DescribeInstanceTypeOfferingsInput{LocationType: types.LocationTypeRegion, MaxResults: aws.Int32(3)},
+			want: want{
+				types:    []string{"m5.large", "m5.xlarge", "c5.large", "c8g.16xlarge"},
+				requests: 2,
+			},
+		},
+		"Filtered": {
+			reason: "Only instance types matching the instance-type filter should be returned.",
+			region: "us-east-1",
+			params: &ec2.DescribeInstanceTypeOfferingsInput{
+				LocationType: types.LocationTypeRegion,
+				Filters:      []types.Filter{{Name: aws.String("instance-type"), Values: []string{"m5.*", "c8g.16xlarge"}}},
+			},
+			want: want{
+				types:    []string{"m5.large", "m5.xlarge", "c8g.16xlarge"},
+				requests: 1,
+			},
+		},
+		"OtherRegion": {
+			reason: "The region should be taken from the request's signature.",
+			region: "af-south-1",
+			params: &ec2.DescribeInstanceTypeOfferingsInput{LocationType: types.LocationTypeRegion},
+			want: want{
+				types:    []string{"m5.large", "c5.large"},
+				requests: 1,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := New(fixture(t))
+			srv := httptest.NewServer(s)
+			defer srv.Close()
+
+			got := []string{}
+			p := ec2.NewDescribeInstanceTypeOfferingsPaginator(client(t, srv, tc.region), tc.params)
+			for p.HasMorePages() {
+				page, err := p.NextPage(context.Background())
+				if err != nil {
+					t.Fatalf("\n%s\nNextPage(...): %v", tc.reason, err)
+				}
+				for _, o := range page.InstanceTypeOfferings {
+					if aws.ToString(o.Location) != tc.region {
+						t.Errorf("\n%s\nNextPage(...): offering %s has location %q, want %q", tc.reason, o.InstanceType, aws.ToString(o.Location), tc.region)
+					}
+					got = append(got, string(o.InstanceType))
+				}
+			}
+			if diff := cmp.Diff(tc.want.types, got); diff != "" {
+				t.Errorf("\n%s\nDescribeInstanceTypeOfferings(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.requests, len(s.Requests())); diff != "" {
+				t.Errorf("\n%s\nRequests(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDescribeInstanceTypes(t *testing.T) {
+	srv := httptest.NewServer(New(fixture(t)))
+	defer srv.Close()
+
+	out, err := client(t, srv, "us-east-1").DescribeInstanceTypes(context.Background(), &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{"m5.large", "c8g.16xlarge"},
+	})
+	if err != nil {
+		t.Fatalf("DescribeInstanceTypes(...): %v", err)
+	}
+
+	type info struct {
+		Type       string
+		VCPUs      int32
+		MemoryMiB  int64
+		ENIs       int32
+		IPv4PerENI int32
+	}
+	want := []info{{"m5.large", 2, 8192, 3, 10}, {"c8g.16xlarge", 64, 131072, 15, 50}}
+	got := make([]info, 0, len(out.InstanceTypes))
+	for _, it := range out.InstanceTypes {
+		got = append(got, info{
+			string(it.InstanceType),
+			aws.ToInt32(it.VCpuInfo.DefaultVCpus),
+			aws.ToInt64(it.MemoryInfo.SizeInMiB),
+			aws.ToInt32(it.NetworkInfo.MaximumNetworkInterfaces),
+			aws.ToInt32(it.NetworkInfo.Ipv4AddressesPerInterface),
+		})
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DescribeInstanceTypes(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestDescribeSpotPriceHistory(t *testing.T) {
+	srv := httptest.NewServer(New(fixture(t)))
+	defer srv.Close()
+
+	out, err := client(t, srv, "us-east-1").DescribeSpotPriceHistory(context.Background(), &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes: []types.InstanceType{"c8g.16xlarge"},
+	})
+	if err != nil {
+		t.Fatalf("DescribeSpotPriceHistory(...): %v", err)
+	}
+	if len(out.SpotPriceHistory) != 1 {
+		t.Fatalf("DescribeSpotPriceHistory(...): got %d prices, want 1", len(out.SpotPriceHistory))
+	}
+	if diff := cmp.Diff("0.9120", aws.ToString(out.SpotPriceHistory[0].SpotPrice)); diff != "" {
+		t.Errorf("DescribeSpotPriceHistory(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestDescribeCapacityReservations(t *testing.T) {
+	srv := httptest.NewServer(New(fixture(t)))
+	defer srv.Close()
+
+	out, err := client(t, srv, "us-east-1").DescribeCapacityReservations(context.Background(), &ec2.DescribeCapacityReservationsInput{
+		Filters: []types.Filter{{Name: aws.String("state"), Values: []string{"active"}}},
+	})
+	if err != nil {
+		t.Fatalf("DescribeCapacityReservations(...): %v", err)
+	}
+	want := []types.CapacityReservation{{
+		CapacityReservationId:  aws.String("cr-0123456789abcdef0"),
+		OwnerId:                aws.String("123456789012"),
+		InstanceType:           aws.String("m5.xlarge"),
+		AvailabilityZone:       aws.String("us-east-1a"),
+		TotalInstanceCount:     aws.Int32(10),
+		AvailableInstanceCount: aws.Int32(4),
+		State:                  types.CapacityReservationStateActive,
+		Tags:                   []types.Tag{{Key: aws.String("purpose"), Value: aws.String("production")}},
+	}}
+	if diff := cmp.Diff(want, out.CapacityReservations, cmpopts.IgnoreUnexported(types.CapacityReservation{}, types.Tag{})); diff != "" {
+		t.Errorf("DescribeCapacityReservations(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestDescribeRegions(t *testing.T) {
+	srv := httptest.NewServer(New(fixture(t)))
+	defer srv.Close()
+
+	out, err := client(t, srv, "us-east-1").DescribeRegions(context.Background(), &ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)})
+	if err != nil {
+		t.Fatalf("DescribeRegions(...): %v", err)
+	}
+	got := make([]string, 0, len(out.Regions))
+	for _, r := range out.Regions {
+		got = append(got, aws.ToString(r.RegionName))
+	}
+	if diff := cmp.Diff([]string{"af-south-1", "us-east-1"}, got); diff != "" {
+		t.Errorf("DescribeRegions(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	type want struct {
+		code     string
+		requests int
+	}
+
+	cases := map[string]struct {
+		reason string
+		region string
+		faults []Fault
+		want   want
+	}{
+		"RetriedFault": {
+			reason: "The SDK should retry a throttled request and succeed.",
+			region: "us-east-1",
+			faults: []Fault{{Status: http.StatusServiceUnavailable, Code: "RequestLimitExceeded", Message: "Request limit exceeded."}},
+			want:   want{requests: 2},
+		},
+		"PersistentFault": {
+			reason: "The SDK should give up after its maximum attempts and return the EC2 error code.",
+			region: "us-east-1",
+			faults: []Fault{
+				{Status: http.StatusServiceUnavailable, Code: "Unavailable", Message: "The service is unavailable."},
+				{Status: http.StatusServiceUnavailable, Code: "Unavailable", Message: "The service is unavailable."},
+				{Status: http.StatusServiceUnavailable, Code: "Unavailable", Message: "The service is unavailable."},
+			},
+			want: want{code: "Unavailable", requests: 3},
+		},
+		"UnknownRegion": {
+			reason: "A region missing from the fixture should fail like a region that isn't enabled.",
+			region: "eu-west-1",
+			want:   want{code: "AuthFailure", requests: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := New(fixture(t), WithFaults(tc.faults...))
+			srv := httptest.NewServer(s)
+			defer srv.Close()
+
+			_, err := client(t, srv, tc.region).DescribeInstanceTypeOfferings(context.Background(), &ec2.DescribeInstanceTypeOfferingsInput{})
+			code := ""
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) {
+				code = apiErr.ErrorCode()
+			} else if err != nil {
+				t.Fatalf("\n%s\nDescribeInstanceTypeOfferings(...): unexpected error %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.code, code); diff != "" {
+				t.Errorf("\n%s\nDescribeInstanceTypeOfferings(...): -want error code, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.requests, len(s.Requests())); diff != "" {
+				t.Errorf("\n%s\nRequests(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	corev1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// v1beta1 values of Karpenter API fields that changed in v1.
+const (
+	karpenterV1Beta1APIVersion       = "karpenter.sh/v1beta1"
+	consolidationPolicyUnderutilized = "WhenUnderutilized"
+)
+
+// nodeClassV1Beta1APIVersions are the API versions of NodeClasses, by kind,
+// that NodePools referenced when Karpenter served v1beta1. Kinds that aren't
+// listed keep their group, at version v1beta1.
+var nodeClassV1Beta1APIVersions = map[string]string{
+	"EC2NodeClass": "karpenter.k8s.aws/v1beta1",
+	"AKSNodeClass": "karpenter.azure.com/v1alpha2",
+}
+
+// newNodePool returns a NodePool with the supplied name that launches nodes of
+// the supplied NodeClass and instance categories, labelled with the supplied
+// category label, within the supplied CPU and memory limits. The supplied
+// requirements further constrain the instance types it launches, and the
+// supplied tier, which may be nil, configures it. It is a karpenter.sh/v1beta1
+// NodePool if the supplied version is v1beta1.
+func newNodePool(name string, nodeClassRef *karpenterv1.NodeClassReference, categoryLabel string, categories []string, reqs []karpenterv1.NodeSelectorRequirementWithMinValues, tier *environmentTier, cpu, memory k8sresource.Quantity, version v1beta1.KarpenterAPIVersion) (*composed.Unstructured, error) {
+	np := &karpenterv1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: karpenterv1.NodePoolSpec{
+			Limits: karpenterv1.Limits{
+				corev1.ResourceCPU:    cpu,
+				corev1.ResourceMemory: memory,
+			},
+			Disruption: karpenterv1.Disruption{
+				ConsolidationPolicy: karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized,
+			},
+			Template: karpenterv1.NodeClaimTemplate{
+				Spec: karpenterv1.NodeClaimTemplateSpec{
+					NodeClassRef: nodeClassRef,
+					Requirements: append([]karpenterv1.NodeSelectorRequirementWithMinValues{
+						{
+							NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+								Key:      categoryLabel,
+								Operator: "In",
+								Values:   categories,
+							},
+						},
+					}, reqs...),
+				},
+			},
+		},
+	}
+	tier.Apply(np)
+
+	composed.Scheme.AddKnownTypes(schema.GroupVersion{Group: "karpenter.sh", Version: "v1"}, &karpenterv1.NodePool{})
+	res, err := composed.From(np)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot convert %T to %T", np, &composed.Unstructured{})
+	}
+	if version == v1beta1.KarpenterV1Beta1 {
+		if err := toKarpenterV1Beta1(res); err != nil {
+			return nil, errors.Wrap(err, "cannot translate NodePool to karpenter.sh/v1beta1")
+		}
+	}
+	return res, nil
+}
+
+// toKarpenterV1Beta1 translates the supplied karpenter.sh/v1 NodePool to a
+// karpenter.sh/v1beta1 NodePool, in place.
+//
+// The NodeClass reference uses apiVersion rather than group, expireAfter
+// moves from the template to the disruption block, WhenEmptyOrUnderutilized
+// consolidation becomes WhenUnderutilized, which doesn't support
+// consolidateAfter, and the v1 only status is dropped.
+func toKarpenterV1Beta1(np *composed.Unstructured) error {
+	o := np.Object
+	np.SetAPIVersion(karpenterV1Beta1APIVersion)
+	delete(o, "status")
+
+	ref, found, err := unstructured.NestedMap(o, "spec", "template", "spec", "nodeClassRef")
+	if err != nil {
+		return errors.Wrap(err, "cannot read spec.template.spec.nodeClassRef")
+	}
+	if found {
+		kind, _ := ref["kind"].(string)
+		apiVersion, ok := nodeClassV1Beta1APIVersions[kind]
+		if !ok {
+			group, _ := ref["group"].(string)
+			apiVersion = group + "/v1beta1"
+		}
+		delete(ref, "group")
+		ref["apiVersion"] = apiVersion
+		if err := unstructured.SetNestedMap(o, ref, "spec", "template", "spec", "nodeClassRef"); err != nil {
+			return errors.Wrap(err, "cannot set spec.template.spec.nodeClassRef")
+		}
+	}
+
+	if expireAfter, found, _ := unstructured.NestedFieldNoCopy(o, "spec", "template", "spec", "expireAfter"); found {
+		unstructured.RemoveNestedField(o, "spec", "template", "spec", "expireAfter")
+		if err := unstructured.SetNestedField(o, expireAfter, "spec", "disruption", "expireAfter"); err != nil {
+			return errors.Wrap(err, "cannot set spec.disruption.expireAfter")
+		}
+	}
+
+	policy, _, _ := unstructured.NestedString(o, "spec", "disruption", "consolidationPolicy")
+	if policy == string(karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized) {
+		if err := unstructured.SetNestedField(o, consolidationPolicyUnderutilized, "spec", "disruption", "consolidationPolicy"); err != nil {
+			return errors.Wrap(err, "cannot set spec.disruption.consolidationPolicy")
+		}
+		unstructured.RemoveNestedField(o, "spec", "disruption", "consolidateAfter")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestToKarpenterV1Beta1(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		v1     map[string]any
+		want   map[string]any
+	}{
+		"WhenEmptyOrUnderutilized": {
+			reason: "WhenEmptyOrUnderutilized should become WhenUnderutilized, dropping consolidateAfter, and expireAfter should move to the disruption block.",
+			v1: map[string]any{
+				"apiVersion": "karpenter.sh/v1",
+				"kind":       "NodePool",
+				"spec": map[string]any{
+					"disruption": map[string]any{
+						"consolidationPolicy": "WhenEmptyOrUnderutilized",
+						"consolidateAfter":    "Never",
+					},
+					"template": map[string]any{
+						"spec": map[string]any{
+							"expireAfter":  "720h",
+							"nodeClassRef": map[string]any{"group": "karpenter.k8s.aws", "kind": "EC2NodeClass", "name": "default"},
+						},
+					},
+				},
+				"status": map[string]any{"nodeClassObservedGeneration": int64(0)},
+			},
+			want: map[string]any{
+				"apiVersion": "karpenter.sh/v1beta1",
+				"kind":       "NodePool",
+				"spec": map[string]any{
+					"disruption": map[string]any{
+						"consolidationPolicy": "WhenUnderutilized",
+						"expireAfter":         "720h",
+					},
+					"template": map[string]any{
+						"spec": map[string]any{
+							"nodeClassRef": map[string]any{"apiVersion": "karpenter.k8s.aws/v1beta1", "kind": "EC2NodeClass", "name": "default"},
+						},
+					},
+				},
+			},
+		},
+		"WhenEmpty": {
+			reason: "WhenEmpty should keep its consolidateAfter, and an unknown NodeClass kind should keep its group.",
+			v1: map[string]any{
+				"apiVersion": "karpenter.sh/v1",
+				"kind":       "NodePool",
+				"spec": map[string]any{
+					"disruption": map[string]any{
+						"consolidationPolicy": "WhenEmpty",
+						"consolidateAfter":    "30s",
+					},
+					"template": map[string]any{
+						"spec": map[string]any{
+							"nodeClassRef": map[string]any{"group": "karpenter.example.org", "kind": "ExampleNodeClass", "name": "default"},
+						},
+					},
+				},
+			},
+			want: map[string]any{
+				"apiVersion": "karpenter.sh/v1beta1",
+				"kind":       "NodePool",
+				"spec": map[string]any{
+					"disruption": map[string]any{
+						"consolidationPolicy": "WhenEmpty",
+						"consolidateAfter":    "30s",
+					},
+					"template": map[string]any{
+						"spec": map[string]any{
+							"nodeClassRef": map[string]any{"apiVersion": "karpenter.example.org/v1beta1", "kind": "ExampleNodeClass", "name": "default"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			np := &composed.Unstructured{Unstructured: unstructured.Unstructured{Object: tc.v1}}
+			if err := toKarpenterV1Beta1(np); err != nil {
+				t.Fatalf("\n%s\ntoKarpenterV1Beta1(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, np.Object); diff != "" {
+				t.Errorf("\n%s\ntoKarpenterV1Beta1(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -10,21 +10,37 @@ import (
 type CLI struct {
 	Debug bool `short:"d" help:"Emit debug logs in addition to info logs."`
 
+	Serve    ServeCmd    `cmd:"" default:"withargs" help:"Serve the Function over gRPC. This is the default command."`
+	Render   RenderCmd   `cmd:"" help:"Render the resources the Function composes for an XR, using an offline offerings catalog."`
+	Validate ValidateCmd `cmd:"" help:"Validate Function Inputs, and the NodePools they produce for sample XRs, against the Function's rules."`
+}
+
+// ServeCmd serves the Function over gRPC.
+type ServeCmd struct {
 	Network            string `help:"Network on which to listen for gRPC connections." default:"tcp"`
 	Address            string `help:"Address at which to listen for gRPC connections." default:":9443"`
 	TLSCertsDir        string `help:"Directory containing server certs (tls.key, tls.crt) and the CA used to verify client certificates (ca.crt)" env:"TLS_SERVER_CERTS_DIR"`
 	Insecure           bool   `help:"Run without mTLS credentials. If you supply this flag --tls-server-certs-dir will be ignored."`
 	MaxRecvMessageSize int    `help:"Maximum size of received messages in MB." default:"4"`
+	EC2Endpoint        string `help:"URL of the EC2 API to call instead of the region's EC2 endpoint, e.g. a local stand-in for testing." env:"EC2_ENDPOINT_URL"`
+	AzureCatalog       string `help:"YAML or JSON file containing the VM SKUs available in each Azure location. Required for XRs that use the Azure provider." env:"AZURE_SKU_CATALOG" type:"existingfile"`
 }
 
 // Run this Function.
-func (c *CLI) Run() error {
-	log, err := function.NewLogger(c.Debug)
+func (c *ServeCmd) Run(cli *CLI) error {
+	log, err := function.NewLogger(cli.Debug)
 	if err != nil {
 		return err
 	}
 
-	return function.Serve(&Function{log: log},
+	f := &Function{log: log, ec2: awsEC2(c.EC2Endpoint)}
+	if c.AzureCatalog != "" {
+		if f.azure, err = loadCatalog(c.AzureCatalog); err != nil {
+			return err
+		}
+	}
+
+	return function.Serve(f,
 		function.Listen(c.Network, c.Address),
 		function.MTLSCertificates(c.TLSCertsDir),
 		function.Insecure(c.Insecure),
@@ -32,6 +48,7 @@ func (c *CLI) Run() error {
 }
 
 func main() {
-	ctx := kong.Parse(&CLI{}, kong.Description("A Crossplane Composition Function."))
-	ctx.FatalIfErrorf(ctx.Run())
+	cli := &CLI{}
+	ctx := kong.Parse(cli, kong.Description("A Crossplane Composition Function."))
+	ctx.FatalIfErrorf(ctx.Run(cli))
 }
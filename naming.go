@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"text/template"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// extraResourceExisting is the key under which the Function requests the
+// existing NodePool, or NodeGroup, with the name it composes.
+const extraResourceExisting = "existing"
+
+// Limits of composed names. NodePool names are used as label values, which
+// can't exceed 63 characters.
+const (
+	maxNameLength  = 63
+	nameHashLength = 8
+)
+
+// Labels Crossplane sets on composite and composed resources.
+const (
+	labelClaimNamespace = "crossplane.io/claim-namespace"
+	labelComposite      = "crossplane.io/composite"
+)
+
+// nameData is the data naming templates may refer to.
+type nameData struct {
+	Name        string
+	Namespace   string
+	Environment string
+	Region      string
+	Provider    string
+}
+
+// composedName returns the name of the NodePool, or NodeGroup, composed for an
+// XR, following the supplied naming strategy. It returns the XR's name if n is
+// nil.
+func composedName(n *v1beta1.Naming, d nameData) (string, error) {
+	if n == nil {
+		return d.Name, nil
+	}
+	prefix, err := renderNameTemplate("prefix", n.Prefix, d)
+	if err != nil {
+		return "", err
+	}
+	suffix, err := renderNameTemplate("suffix", n.Suffix, d)
+	if err != nil {
+		return "", err
+	}
+
+	base := d.Name
+	if n.IncludeNamespace && d.Namespace != "" {
+		base = d.Namespace + "-" + base
+	}
+	name := truncateName(prefix + base + suffix)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", errors.Errorf("name %q is invalid: %s", name, strings.Join(errs, "; "))
+	}
+	return name, nil
+}
+
+// renderNameTemplate renders the supplied naming template.
+func renderNameTemplate(field, tmpl string, d nameData) (string, error) {
+	if tmpl == "" {
+		return "", nil
+	}
+	t, err := template.New(field).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot parse naming %s", field)
+	}
+	b := &strings.Builder{}
+	if err := t.Execute(b, d); err != nil {
+		return "", errors.Wrapf(err, "cannot render naming %s", field)
+	}
+	return b.String(), nil
+}
+
+// truncateName truncates names longer than maxNameLength, replacing their
+// end with a hash of the full name so that truncated names stay unique.
+func truncateName(name string) string {
+	if len(name) <= maxNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:nameHashLength]
+	return strings.TrimRight(name[:maxNameLength-nameHashLength-1], "-.") + "-" + hash
+}
+
+// xrNamespace returns the namespace of the supplied XR, or of its claim if the
+// XR is cluster scoped.
+func xrNamespace(xr *resource.Composite) string {
+	if ns := xr.Resource.GetNamespace(); ns != "" {
+		return ns
+	}
+	return xr.Resource.GetLabels()[labelClaimNamespace]
+}
+
+// existingRequirement returns a selector for the existing resource with the
+// supplied name and the supplied resource's kind.
+func existingRequirement(apiVersion, kind, name string) *fnv1.ResourceSelector {
+	return &fnv1.ResourceSelector{
+		ApiVersion: apiVersion,
+		Kind:       kind,
+		Match:      &fnv1.ResourceSelector_MatchName{MatchName: name},
+	}
+}
+
+// checkExisting returns an error if the existing resource the supplied
+// selector matches belongs to a composite resource other than the supplied XR,
+// which composes it under the supplied name; see checkNameClash. It requires
+// the existing resource as an extra resource, and returns false if Crossplane
+// hasn't fetched it yet.
+func (f *Function) checkExisting(req *fnv1.RunFunctionRequest, rsp *fnv1.RunFunctionResponse, sel *fnv1.ResourceSelector, name resource.Name, xr *resource.Composite, extra map[string][]resource.Extra) (bool, error) {
+	requireExtraResource(rsp, extraResourceExisting, sel)
+	existing, ok := extra[extraResourceExisting]
+	if !ok {
+		f.log.Debug("Waiting for existing resource", "kind", sel.GetKind(), "name", sel.GetMatchName())
+		return false, nil
+	}
+	observed, err := observedComposed(req, name)
+	if err != nil {
+		return false, err
+	}
+	return true, checkNameClash(existing, observed, xr)
+}
+
+// checkNameClash returns an error if the supplied existing resource belongs to
+// a composite resource other than the supplied XR. Resources the XR already
+// composes, or that aren't there, don't clash. A resource's owner references
+// say which XR it belongs to. Its crossplane.io/composite label, which names
+// rather than identifies an XR, is only trusted when it has none.
+func checkNameClash(existing []resource.Extra, observed map[string]any, xr *resource.Composite) error {
+	if len(existing) == 0 {
+		return nil
+	}
+	e := existing[0].Resource
+	if observed != nil && (&unstructured.Unstructured{Object: observed}).GetName() == e.GetName() {
+		return nil
+	}
+	uid := xr.Resource.GetUID()
+	if refs := e.GetOwnerReferences(); len(refs) > 0 {
+		owner := refs[0]
+		if c := metav1.GetControllerOfNoCopy(e); c != nil {
+			refs = []metav1.OwnerReference{*c}
+			owner = *c
+		}
+		for _, ref := range refs {
+			if uid != "" && ref.UID == uid {
+				return nil
+			}
+		}
+		return errors.Errorf("%s %q already exists and is owned by %s %q with UID %q", e.GetKind(), e.GetName(), owner.Kind, owner.Name, owner.UID)
+	}
+	owner := e.GetLabels()[labelComposite]
+	if owner == xr.Resource.GetName() {
+		return nil
+	}
+	if owner == "" {
+		return errors.Errorf("%s %q already exists and isn't owned by a composite resource", e.GetKind(), e.GetName())
+	}
+	return errors.Errorf("%s %q already exists and is owned by composite resource %q", e.GetKind(), e.GetName(), owner)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestComposedName(t *testing.T) {
+	data := nameData{Name: "np1", Namespace: "team-a", Environment: "production", Region: "us-east-1", Provider: "aws"}
+	long := strings.Repeat("a", 70)
+
+	type want struct {
+		name string
+		err  string
+	}
+
+	cases := map[string]struct {
+		reason string
+		naming *v1beta1.Naming
+		data   nameData
+		want   want
+	}{
+		"Default": {
+			reason: "The XR's name should be used when the Input has no naming strategy.",
+			data:   data,
+			want:   want{name: "np1"},
+		},
+		"Templates": {
+			reason: "The prefix and suffix templates should be rendered with the XR's fields.",
+			naming: &v1beta1.Naming{Prefix: "{{ .Environment }}-", Suffix: "-{{ .Region }}"},
+			data:   data,
+			want:   want{name: "production-np1-us-east-1"},
+		},
+		"IncludeNamespace": {
+			reason: "The XR's namespace should be included in the name when asked.",
+			naming: &v1beta1.Naming{IncludeNamespace: true},
+			data:   data,
+			want:   want{name: "team-a-np1"},
+		},
+		"Truncated": {
+			reason: "Names longer than 63 characters should be truncated and suffixed with a hash of the full name.",
+			naming: &v1beta1.Naming{Suffix: "-pool"},
+			data:   nameData{Name: long},
+			want:   want{name: strings.Repeat("a", 54) + "-" + truncateName(long + "-pool")[55:]},
+		},
+		"UnknownField": {
+			reason: "A template referring to an unknown field should return an error.",
+			naming: &v1beta1.Naming{Prefix: "{{ .Cluster }}-"},
+			data:   data,
+			want:   want{err: `cannot render naming prefix: template: prefix:1:3: executing "prefix" at <.Cluster>: can't evaluate field Cluster in type main.nameData`},
+		},
+		"Invalid": {
+			reason: "A name that isn't a valid Kubernetes name should return an error.",
+			naming: &v1beta1.Naming{Prefix: "Pool_"},
+			data:   data,
+			want:   want{err: `name "Pool_np1" is invalid: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			n, err := composedName(tc.naming, tc.data)
+			got := want{name: n}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ncomposedName(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if len(got.name) > maxNameLength {
+				t.Errorf("\n%s\ncomposedName(...): %q is longer than %d characters", tc.reason, got.name, maxNameLength)
+			}
+		})
+	}
+}
+
+func TestCheckNameClash(t *testing.T) {
+	xr := &resource.Composite{Resource: composite.New()}
+	xr.Resource.SetName("np1")
+	xr.Resource.SetUID(types.UID("xr-uid"))
+
+	nodePool := func(labels map[string]string, owners ...metav1.OwnerReference) []resource.Extra {
+		u := &unstructured.Unstructured{}
+		u.SetKind("NodePool")
+		u.SetName("production-np1")
+		u.SetLabels(labels)
+		u.SetOwnerReferences(owners)
+		return []resource.Extra{{Resource: u}}
+	}
+
+	cases := map[string]struct {
+		reason   string
+		existing []resource.Extra
+		observed map[string]any
+		want     string
+	}{
+		"NotExisting": {
+			reason: "A name that isn't taken shouldn't clash.",
+		},
+		"OwnedByXR": {
+			reason:   "A NodePool owned by the XR shouldn't clash.",
+			existing: nodePool(nil, metav1.OwnerReference{UID: "xr-uid"}),
+		},
+		"ComposedByXR": {
+			reason:   "A NodePool the XR already composes shouldn't clash.",
+			existing: nodePool(map[string]string{labelComposite: "other"}),
+			observed: map[string]any{"metadata": map[string]any{"name": "production-np1"}},
+		},
+		"OwnedByOtherXR": {
+			reason:   "A NodePool owned by another XR should clash.",
+			existing: nodePool(map[string]string{labelComposite: "other"}, metav1.OwnerReference{Kind: "XNodePool", Name: "other", UID: "other-uid"}),
+			want:     `NodePool "production-np1" already exists and is owned by XNodePool "other" with UID "other-uid"`,
+		},
+		"ControlledByOtherXRWithSameName": {
+			reason:   "A NodePool controlled by another XR should clash even if its composite label names the XR, like one composed by a deleted XR of the same name.",
+			existing: nodePool(map[string]string{labelComposite: "np1"}, metav1.OwnerReference{Kind: "XNodePool", Name: "np1", UID: "old-uid", Controller: ptr.To(true)}),
+			want:     `NodePool "production-np1" already exists and is owned by XNodePool "np1" with UID "old-uid"`,
+		},
+		"LabelledByXR": {
+			reason:   "A NodePool without owner references whose composite label names the XR shouldn't clash.",
+			existing: nodePool(map[string]string{labelComposite: "np1"}),
+		},
+		"LabelledByOtherXR": {
+			reason:   "A NodePool without owner references whose composite label names another XR should clash.",
+			existing: nodePool(map[string]string{labelComposite: "other"}),
+			want:     `NodePool "production-np1" already exists and is owned by composite resource "other"`,
+		},
+		"Unowned": {
+			reason:   "A NodePool no XR owns should clash.",
+			existing: nodePool(nil),
+			want:     `NodePool "production-np1" already exists and isn't owned by a composite resource`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			if err := checkNameClash(tc.existing, tc.observed, xr); err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckNameClash(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
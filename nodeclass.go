@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// extraResourceNodeClasses is the key under which the Function requests the
+// NodeClasses it selects from.
+const extraResourceNodeClasses = "nodeclasses"
+
+// Default labels of a NodeClassSelector.
+const (
+	defaultNodeClassEnvironmentLabel = labelEnvironment
+	defaultNodeClassRegionLabel      = "topology.kubernetes.io/region"
+)
+
+// nodeClassAPIVersions are the API versions the Function requests NodeClasses
+// at, by kind.
+var nodeClassAPIVersions = map[string]string{
+	"EC2NodeClass": "karpenter.k8s.aws/v1",
+	"AKSNodeClass": "karpenter.azure.com/v1beta1",
+	"NodeClass":    autoModeNodeClassAPIVersion,
+}
+
+// requireExtraResource adds a requirement for the extra resources matching the
+// supplied selector to the response, under the supplied key.
+func requireExtraResource(rsp *fnv1.RunFunctionResponse, key string, sel *fnv1.ResourceSelector) {
+	if rsp.GetRequirements() == nil {
+		rsp.Requirements = &fnv1.Requirements{}
+	}
+	if rsp.GetRequirements().GetExtraResources() == nil {
+		rsp.Requirements.ExtraResources = map[string]*fnv1.ResourceSelector{}
+	}
+	rsp.Requirements.ExtraResources[key] = sel
+}
+
+// nodeClassRequirement returns a selector for the NodeClasses of the supplied
+// kind that the supplied selector's labels match.
+func nodeClassRequirement(kind string, s *v1beta1.NodeClassSelector) *fnv1.ResourceSelector {
+	return &fnv1.ResourceSelector{
+		ApiVersion: nodeClassAPIVersions[kind],
+		Kind:       kind,
+		Match:      &fnv1.ResourceSelector_MatchLabels{MatchLabels: &fnv1.MatchLabels{Labels: s.MatchLabels}},
+	}
+}
+
+// loadNodeClass returns the name of the NodeClass of the supplied kind that
+// the supplied selector selects for the supplied environment and region; see
+// selectNodeClass. It requires the candidate NodeClasses as extra resources,
+// and returns false if Crossplane hasn't fetched them yet.
+func (f *Function) loadNodeClass(rsp *fnv1.RunFunctionResponse, kind string, s *v1beta1.NodeClassSelector, environment, region string, extra map[string][]resource.Extra) (string, bool, error) {
+	requireExtraResource(rsp, extraResourceNodeClasses, nodeClassRequirement(kind, s))
+	candidates, ok := extra[extraResourceNodeClasses]
+	if !ok {
+		f.log.Debug("Waiting for NodeClasses", "kind", kind, "matchLabels", s.MatchLabels)
+		return "", false, nil
+	}
+	name, err := selectNodeClass(candidates, kind, s, environment, region)
+	if err != nil {
+		return "", false, err
+	}
+	f.log.Debug("Selected NodeClass", "kind", kind, "name", name)
+	return name, true, nil
+}
+
+// selectNodeClass returns the name of the only NodeClass labelled with the
+// supplied environment and region. It returns an error describing the
+// candidates if none or several are.
+func selectNodeClass(candidates []resource.Extra, kind string, s *v1beta1.NodeClassSelector, environment, region string) (string, error) {
+	envLabel := withDefault(s.EnvironmentLabel, defaultNodeClassEnvironmentLabel)
+	regionLabel := withDefault(s.RegionLabel, defaultNodeClassRegionLabel)
+
+	var matched, rejected []string
+	for _, c := range candidates {
+		l := c.Resource.GetLabels()
+		if l[envLabel] == environment && l[regionLabel] == region {
+			matched = append(matched, c.Resource.GetName())
+			continue
+		}
+		rejected = append(rejected, fmt.Sprintf("%s (%s=%q, %s=%q)", c.Resource.GetName(), envLabel, l[envLabel], regionLabel, l[regionLabel]))
+	}
+	sort.Strings(matched)
+	sort.Strings(rejected)
+
+	switch {
+	case len(candidates) == 0:
+		return "", errors.Errorf("no %s matches labels %s", kind, formatLabels(s.MatchLabels))
+	case len(matched) == 0:
+		return "", errors.Errorf("none of the %d %ses matching labels %s has %s=%q and %s=%q: %s",
+			len(candidates), kind, formatLabels(s.MatchLabels), envLabel, environment, regionLabel, region, strings.Join(rejected, ", "))
+	case len(matched) > 1:
+		return "", errors.Errorf("%d %ses matching labels %s have %s=%q and %s=%q, expected exactly one: %s",
+			len(matched), kind, formatLabels(s.MatchLabels), envLabel, environment, regionLabel, region, strings.Join(matched, ", "))
+	}
+	return matched[0], nil
+}
+
+// formatLabels formats labels as a sorted, comma separated list of key=value
+// pairs in braces.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestSelectNodeClass(t *testing.T) {
+	nodeClass := func(name, env, region string) resource.Extra {
+		u := &unstructured.Unstructured{}
+		u.SetName(name)
+		u.SetLabels(map[string]string{
+			"nodepools.fn.crossplane.io/environment": env,
+			"topology.kubernetes.io/region":          region,
+		})
+		return resource.Extra{Resource: u}
+	}
+	selector := &v1beta1.NodeClassSelector{MatchLabels: map[string]string{"team": "platform"}}
+
+	type want struct {
+		name string
+		err  string
+	}
+
+	cases := map[string]struct {
+		reason     string
+		candidates []resource.Extra
+		want       want
+	}{
+		"OneMatch": {
+			reason:     "The NodeClass labelled with the XR's environment and region should be selected.",
+			candidates: []resource.Extra{nodeClass("dev", "development", "us-east-1"), nodeClass("prod", "production", "us-east-1")},
+			want:       want{name: "prod"},
+		},
+		"NoCandidates": {
+			reason: "An error naming the labels should be returned when no NodeClass matches them.",
+			want:   want{err: "no EC2NodeClass matches labels {team=platform}"},
+		},
+		"NoMatch": {
+			reason:     "An error listing every candidate's environment and region should be returned when none match the XR's.",
+			candidates: []resource.Extra{nodeClass("prod-west", "production", "us-west-2"), nodeClass("dev", "development", "us-east-1")},
+			want: want{err: `none of the 2 EC2NodeClasses matching labels {team=platform} has nodepools.fn.crossplane.io/environment="production" and topology.kubernetes.io/region="us-east-1": ` +
+				`dev (nodepools.fn.crossplane.io/environment="development", topology.kubernetes.io/region="us-east-1"), ` +
+				`prod-west (nodepools.fn.crossplane.io/environment="production", topology.kubernetes.io/region="us-west-2")`},
+		},
+		"SeveralMatch": {
+			reason:     "An error naming every matching NodeClass should be returned when several match the XR's environment and region.",
+			candidates: []resource.Extra{nodeClass("prod-b", "production", "us-east-1"), nodeClass("prod-a", "production", "us-east-1")},
+			want: want{err: `2 EC2NodeClasses matching labels {team=platform} have nodepools.fn.crossplane.io/environment="production" and topology.kubernetes.io/region="us-east-1", ` +
+				`expected exactly one: prod-a, prod-b`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			n, err := selectNodeClass(tc.candidates, "EC2NodeClass", selector, "production", "us-east-1")
+			got := want{name: n}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nselectNodeClass(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// Autoscalers an XR may select with spec.Autoscaler.
+const (
+	autoscalerKarpenter         = "karpenter"
+	autoscalerEKSAutoMode       = "eks-auto-mode"
+	autoscalerClusterAutoscaler = "cluster-autoscaler"
+)
+
+// composesNodePool returns true if the supplied autoscaler scales Karpenter
+// NodePools.
+func composesNodePool(autoscaler string) bool {
+	return autoscaler == autoscalerKarpenter || autoscaler == autoscalerEKSAutoMode
+}
+
+// nodeGroupAPIVersion is the API version of provider-aws EKS NodeGroups.
+const nodeGroupAPIVersion = "eks.aws.upbound.io/v1beta1"
+
+// defaultNodeGroupMinSize is the smallest number of nodes a node group scales
+// in to when the Input doesn't say.
+const defaultNodeGroupMinSize = 1
+
+// A nodeShape is the instance types of one instance category that a node
+// group may launch, and the vCPUs and memory each of them has.
+type nodeShape struct {
+	InstanceTypes []string
+	CPU           k8sresource.Quantity
+	Memory        k8sresource.Quantity
+}
+
+// nodeGroupShapes are the node shapes a node group uses for each AWS instance
+// category. Categories without a shape use the m category's shape.
+var nodeGroupShapes = map[string]nodeShape{
+	"c": {InstanceTypes: []string{"c6i.xlarge", "c5.xlarge"}, CPU: k8sresource.MustParse("4"), Memory: k8sresource.MustParse("8Gi")},
+	"m": {InstanceTypes: []string{"m6i.xlarge", "m5.xlarge"}, CPU: k8sresource.MustParse("4"), Memory: k8sresource.MustParse("16Gi")},
+	"r": {InstanceTypes: []string{"r6i.xlarge", "r5.xlarge"}, CPU: k8sresource.MustParse("4"), Memory: k8sresource.MustParse("32Gi")},
+	"t": {InstanceTypes: []string{"t3.xlarge"}, CPU: k8sresource.MustParse("4"), Memory: k8sresource.MustParse("16Gi")},
+}
+
+// nodeGroupMaxSize returns the most nodes of the supplied shapes that fit the
+// supplied limits, assuming every node is as large as the largest shape. It
+// returns at least minSize.
+func nodeGroupMaxSize(shapes []nodeShape, cpu, memory k8sresource.Quantity, minSize int64) int64 {
+	var nodeCPU, nodeMemory k8sresource.Quantity
+	for _, s := range shapes {
+		if s.CPU.Cmp(nodeCPU) > 0 {
+			nodeCPU = s.CPU
+		}
+		if s.Memory.Cmp(nodeMemory) > 0 {
+			nodeMemory = s.Memory
+		}
+	}
+	size := min(cpu.MilliValue()/nodeCPU.MilliValue(), memory.Value()/nodeMemory.Value())
+	return max(size, minSize, 1)
+}
+
+// offeredShapes returns the node shapes of the supplied categories, narrowed to
+// their instance types offered in the region. Categories without a node shape
+// use the m category's. Shapes without an offered instance type are omitted.
+func offeredShapes(categories, offered []string) []nodeShape {
+	shapes := make([]nodeShape, 0, len(categories))
+	for _, c := range categories {
+		s, ok := nodeGroupShapes[c]
+		if !ok {
+			s = nodeGroupShapes["m"]
+		}
+		s.InstanceTypes = slices.DeleteFunc(slices.Clone(s.InstanceTypes), func(t string) bool {
+			return !slices.Contains(offered, t)
+		})
+		if len(s.InstanceTypes) > 0 {
+			shapes = append(shapes, s)
+		}
+	}
+	return shapes
+}
+
+// nodeGroupConfig returns a copy of the Input's supplied NodeGroup
+// configuration with the supplied node role, if any, and the supplied tier's
+// taints. It returns an error if the node role isn't in the supplied region's
+// partition, and nil if cfg is nil.
+func nodeGroupConfig(cfg *v1beta1.NodeGroup, nodeRoleARN, region string, tier *environmentTier) (*v1beta1.NodeGroup, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	cfg = cfg.DeepCopy()
+	if nodeRoleARN != "" {
+		cfg.NodeRoleARN = nodeRoleARN
+	}
+	if err := checkARNPartition(cfg.NodeRoleARN, region); err != nil {
+		return nil, errors.Wrap(err, "invalid node role")
+	}
+	cfg.Taints = append(cfg.Taints, tier.NodeGroupTaints()...)
+	return cfg, nil
+}
+
+// newNodeGroup returns a Crossplane provider-aws EKS NodeGroup that launches
+// the offered instances of the supplied categories, sized to the supplied
+// limits.
+func newNodeGroup(name, region, clusterName string, cfg *v1beta1.NodeGroup, categories, offered []string, cpu, memory k8sresource.Quantity) (*composed.Unstructured, error) {
+	if cfg == nil {
+		return nil, errors.New("the Input's nodeGroup must be set to compose a NodeGroup")
+	}
+
+	shapes := offeredShapes(categories, offered)
+	if len(shapes) == 0 {
+		return nil, errors.Errorf("none of the instance types node groups of categories %v launch are offered", categories)
+	}
+	var instanceTypes []any
+	for _, s := range shapes {
+		for _, t := range s.InstanceTypes {
+			instanceTypes = append(instanceTypes, t)
+		}
+	}
+
+	minSize := int64(defaultNodeGroupMinSize)
+	if cfg.MinSize != nil {
+		minSize = *cfg.MinSize
+	}
+	maxSize := nodeGroupMaxSize(shapes, cpu, memory, minSize)
+
+	subnets := make([]any, len(cfg.SubnetIDs))
+	for i, id := range cfg.SubnetIDs {
+		subnets[i] = id
+	}
+
+	forProvider := map[string]any{
+		"region":        region,
+		"clusterName":   clusterName,
+		"nodeRoleArn":   cfg.NodeRoleARN,
+		"subnetIds":     subnets,
+		"instanceTypes": instanceTypes,
+		"scalingConfig": []any{map[string]any{
+			"minSize":     minSize,
+			"maxSize":     maxSize,
+			"desiredSize": minSize,
+		}},
+	}
+	if len(cfg.Labels) > 0 {
+		labels := make(map[string]any, len(cfg.Labels))
+		for k, v := range cfg.Labels {
+			labels[k] = v
+		}
+		forProvider["labels"] = labels
+	}
+	if len(cfg.Taints) > 0 {
+		taints := make([]any, len(cfg.Taints))
+		for i, t := range cfg.Taints {
+			taint := map[string]any{"key": t.Key, "effect": t.Effect}
+			if t.Value != "" {
+				taint["value"] = t.Value
+			}
+			taints[i] = taint
+		}
+		forProvider["taint"] = taints
+	}
+
+	ng := composed.New()
+	ng.SetAPIVersion(nodeGroupAPIVersion)
+	ng.SetKind("NodeGroup")
+	ng.SetName(name)
+	if err := ng.SetValue("spec.forProvider", forProvider); err != nil {
+		return nil, errors.Wrap(err, "cannot set spec.forProvider of NodeGroup")
+	}
+	return ng, nil
+}
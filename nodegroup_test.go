@@ -0,0 +1,189 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestNodeGroupMaxSize(t *testing.T) {
+	type args struct {
+		categories []string
+		cpu        string
+		memory     string
+		minSize    int64
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   int64
+	}{
+		"LargestShape": {
+			reason: "The max size should fit the limits assuming every node is the largest shape of the group.",
+			args:   args{categories: []string{"m", "c"}, cpu: "40", memory: "64Gi", minSize: 1},
+			want:   4,
+		},
+		"CPUBound": {
+			reason: "The max size should be bounded by the CPU limit when it is the tighter limit.",
+			args:   args{categories: []string{"c"}, cpu: "8", memory: "64Gi", minSize: 1},
+			want:   2,
+		},
+		"AtLeastOne": {
+			reason: "The max size should be at least one node even if a node exceeds the limits.",
+			args:   args{categories: []string{"m"}, cpu: "1000m", memory: "1000Mi", minSize: 0},
+			want:   1,
+		},
+		"AtLeastMinSize": {
+			reason: "The max size should never be smaller than the min size.",
+			args:   args{categories: []string{"m"}, cpu: "1000m", memory: "1000Mi", minSize: 3},
+			want:   3,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			shapes := make([]nodeShape, len(tc.args.categories))
+			for i, c := range tc.args.categories {
+				shapes[i] = nodeGroupShapes[c]
+			}
+			got := nodeGroupMaxSize(shapes, k8sresource.MustParse(tc.args.cpu), k8sresource.MustParse(tc.args.memory), tc.args.minSize)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nnodeGroupMaxSize(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewNodeGroup(t *testing.T) {
+	cfg := &v1beta1.NodeGroup{NodeRoleARN: "arn:aws:iam::123456789012:role/nodes"}
+
+	type want struct {
+		instanceTypes any
+		maxSize       any
+		err           string
+	}
+
+	cases := map[string]struct {
+		reason     string
+		cfg        *v1beta1.NodeGroup
+		categories []string
+		offered    []string
+		want       want
+	}{
+		"OfferedInstanceTypes": {
+			reason:     "The node group should launch only the instance types of its categories offered in the region.",
+			cfg:        cfg,
+			categories: []string{"m", "c"},
+			offered:    []string{"m6i.xlarge", "c5.xlarge", "c5.large"},
+			want:       want{instanceTypes: []any{"m6i.xlarge", "c5.xlarge"}, maxSize: int64(2)},
+		},
+		"CategoryNotOffered": {
+			reason:     "Categories without an offered instance type shouldn't size the node group.",
+			cfg:        cfg,
+			categories: []string{"r", "c"},
+			offered:    []string{"c6i.xlarge"},
+			want:       want{instanceTypes: []any{"c6i.xlarge"}, maxSize: int64(2)},
+		},
+		"NoneOffered": {
+			reason:     "An error should be returned if no instance type of the categories is offered.",
+			cfg:        cfg,
+			categories: []string{"m", "c"},
+			offered:    []string{"m5.large", "c5.large"},
+			want:       want{err: "none of the instance types node groups of categories [m c] launch are offered"},
+		},
+		"NoConfig": {
+			reason: "An error should be returned if the Input doesn't configure node groups.",
+			want:   want{err: "the Input's nodeGroup must be set to compose a NodeGroup"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ng, err := newNodeGroup("np1", "us-east-1", "cluster", tc.cfg, tc.categories, tc.offered, k8sresource.MustParse("8"), k8sresource.MustParse("32Gi"))
+			got := want{}
+			if err != nil {
+				got.err = err.Error()
+			} else {
+				got.instanceTypes, _ = ng.GetValue("spec.forProvider.instanceTypes")
+				got.maxSize, _ = ng.GetValue("spec.forProvider.scalingConfig[0].maxSize")
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nnewNodeGroup(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNodeGroupConfig(t *testing.T) {
+	cfg := &v1beta1.NodeGroup{
+		NodeRoleARN: "arn:aws:iam::123456789012:role/nodes",
+		Taints:      []v1beta1.Taint{{Key: "dedicated", Value: "batch", Effect: "NO_SCHEDULE"}},
+	}
+	tier := &environmentTier{Taints: []corev1.Taint{{Key: "tier", Value: "gold", Effect: corev1.TaintEffectNoExecute}}}
+
+	type want struct {
+		cfg *v1beta1.NodeGroup
+		err string
+	}
+
+	cases := map[string]struct {
+		reason      string
+		cfg         *v1beta1.NodeGroup
+		nodeRoleARN string
+		region      string
+		tier        *environmentTier
+		want        want
+	}{
+		"NoConfig": {
+			reason: "No configuration should be returned if the Input has none.",
+			region: "us-east-1",
+		},
+		"TierTaints": {
+			reason: "The tier's taints should be appended to the Input's.",
+			cfg:    cfg,
+			region: "us-east-1",
+			tier:   tier,
+			want: want{cfg: &v1beta1.NodeGroup{
+				NodeRoleARN: "arn:aws:iam::123456789012:role/nodes",
+				Taints: []v1beta1.Taint{
+					{Key: "dedicated", Value: "batch", Effect: "NO_SCHEDULE"},
+					{Key: "tier", Value: "gold", Effect: "NO_EXECUTE"},
+				},
+			}},
+		},
+		"ContextNodeRole": {
+			reason:      "The node role from the pipeline Context should override the Input's.",
+			cfg:         cfg,
+			nodeRoleARN: "arn:aws-cn:iam::123456789012:role/nodes",
+			region:      "cn-north-1",
+			want: want{cfg: &v1beta1.NodeGroup{
+				NodeRoleARN: "arn:aws-cn:iam::123456789012:role/nodes",
+				Taints:      []v1beta1.Taint{{Key: "dedicated", Value: "batch", Effect: "NO_SCHEDULE"}},
+			}},
+		},
+		"NodeRoleInOtherPartition": {
+			reason: "A node role outside the region's partition should return an error.",
+			cfg:    cfg,
+			region: "cn-north-1",
+			want:   want{err: `invalid node role: ARN "arn:aws:iam::123456789012:role/nodes" is in partition "aws", but region "cn-north-1" is in partition "aws-cn"`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := nodeGroupConfig(tc.cfg, tc.nodeRoleARN, tc.region, tc.tier)
+			g := want{cfg: got}
+			if err != nil {
+				g.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, g, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nnodeGroupConfig(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// nodeOverlayAPIVersion is the API version of Karpenter NodeOverlays, which
+// Karpenter serves from v1.6.
+const nodeOverlayAPIVersion = "karpenter.sh/v1alpha1"
+
+// Formats of NodeOverlay prices, per the NodeOverlay API.
+var (
+	priceAdjustmentFormat = regexp.MustCompile(`^[+-]\d*\.?\d+%?$`)
+	priceFormat           = regexp.MustCompile(`^\d*\.?\d+$`)
+)
+
+// nodeOverlayResourceName returns the name of the composed resource of the
+// supplied Input overlay.
+func nodeOverlayResourceName(o v1beta1.NodeOverlay) resource.Name {
+	return resource.Name("nodeoverlay-" + o.Name)
+}
+
+// newNodeOverlays returns a Karpenter NodeOverlay for each of the supplied
+// Input overlays, keyed by composed resource name. Each applies to the
+// instance types the supplied NodePool may launch, of the overlay's families
+// and capacity types, when Karpenter schedules that NodePool.
+func newNodeOverlays(overlays []v1beta1.NodeOverlay, nodePool *composed.Unstructured, familyLabel string, labels map[string]string) (map[resource.Name]*composed.Unstructured, error) {
+	if len(overlays) == 0 {
+		return nil, nil
+	}
+	reqs, _, err := unstructured.NestedSlice(nodePool.Object, "spec", "template", "spec", "requirements")
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read requirements of %s %q", nodePool.GetKind(), nodePool.GetName())
+	}
+	base := []any{map[string]any{
+		"key":      karpenterv1.NodePoolLabelKey,
+		"operator": "In",
+		"values":   []any{nodePool.GetName()},
+	}}
+	for _, r := range reqs {
+		m, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		// Overlay requirements don't support minValues.
+		base = append(base, map[string]any{"key": m["key"], "operator": m["operator"], "values": m["values"]})
+	}
+
+	out := make(map[resource.Name]*composed.Unstructured, len(overlays))
+	for _, o := range overlays {
+		name := nodeOverlayResourceName(o)
+		if _, ok := out[name]; ok {
+			return nil, errors.Errorf("the Input has more than one NodeOverlay named %q", o.Name)
+		}
+		spec, err := nodeOverlaySpec(o, base, familyLabel)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid NodeOverlay %q", o.Name)
+		}
+		no := composed.New()
+		no.SetAPIVersion(nodeOverlayAPIVersion)
+		no.SetKind("NodeOverlay")
+		no.SetName(truncateName(nodePool.GetName() + "-" + o.Name))
+		no.SetLabels(labels)
+		if err := no.SetValue("spec", spec); err != nil {
+			return nil, errors.Wrapf(err, "cannot set spec of NodeOverlay %q", o.Name)
+		}
+		out[name] = no
+	}
+	return out, nil
+}
+
+// nodeOverlaySpec returns the spec of the NodeOverlay of the supplied Input
+// overlay, adding its families and capacity types to the supplied NodePool
+// requirements.
+func nodeOverlaySpec(o v1beta1.NodeOverlay, base []any, familyLabel string) (map[string]any, error) {
+	spec := map[string]any{}
+	switch {
+	case o.PriceAdjustment != "" && o.Price != "":
+		return nil, errors.New("priceAdjustment and price are mutually exclusive")
+	case o.PriceAdjustment != "":
+		if !priceAdjustmentFormat.MatchString(o.PriceAdjustment) {
+			return nil, errors.Errorf("priceAdjustment %q must be a signed amount, e.g. -0.02, or percentage, e.g. -15%%", o.PriceAdjustment)
+		}
+		spec["priceAdjustment"] = o.PriceAdjustment
+	case o.Price != "":
+		if !priceFormat.MatchString(o.Price) {
+			return nil, errors.Errorf("price %q must be an amount, e.g. 0.25", o.Price)
+		}
+		spec["price"] = o.Price
+	default:
+		return nil, errors.New("one of priceAdjustment and price must be set")
+	}
+
+	reqs := append([]any{}, base...)
+	if len(o.Families) > 0 {
+		values := make([]any, len(o.Families))
+		for i, f := range o.Families {
+			values[i] = f
+		}
+		reqs = append(reqs, map[string]any{"key": familyLabel, "operator": "In", "values": values})
+	}
+	if len(o.CapacityTypes) > 0 {
+		values := make([]any, len(o.CapacityTypes))
+		for i, c := range o.CapacityTypes {
+			values[i] = string(c)
+		}
+		reqs = append(reqs, map[string]any{"key": karpenterv1.CapacityTypeLabelKey, "operator": "In", "values": values})
+	}
+	spec["requirements"] = reqs
+	if o.Weight != nil {
+		spec["weight"] = int64(*o.Weight)
+	}
+	return spec, nil
+}
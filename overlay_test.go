@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestNodeOverlaySpec(t *testing.T) {
+	base := []any{map[string]any{"key": "karpenter.sh/nodepool", "operator": "In", "values": []any{"np1"}}}
+	weight := int32(5)
+
+	type want struct {
+		spec map[string]any
+		err  string
+	}
+
+	cases := map[string]struct {
+		reason  string
+		overlay v1beta1.NodeOverlay
+		want    want
+	}{
+		"PriceAdjustment": {
+			reason:  "An overlay should adjust the price of its families and capacity types.",
+			overlay: v1beta1.NodeOverlay{Families: []string{"m6i"}, CapacityTypes: []v1beta1.CapacityType{"spot"}, PriceAdjustment: "-0.02", Weight: &weight},
+			want: want{spec: map[string]any{
+				"priceAdjustment": "-0.02",
+				"weight":          int64(5),
+				"requirements": []any{
+					base[0],
+					map[string]any{"key": "karpenter.k8s.aws/instance-family", "operator": "In", "values": []any{"m6i"}},
+					map[string]any{"key": "karpenter.sh/capacity-type", "operator": "In", "values": []any{"spot"}},
+				},
+			}},
+		},
+		"Price": {
+			reason:  "An overlay without families or capacity types should replace the price of every instance type of the NodePool.",
+			overlay: v1beta1.NodeOverlay{Price: "0.25"},
+			want:    want{spec: map[string]any{"price": "0.25", "requirements": base}},
+		},
+		"Both": {
+			reason:  "An overlay shouldn't set both a price and a price adjustment.",
+			overlay: v1beta1.NodeOverlay{Price: "0.25", PriceAdjustment: "-10%"},
+			want:    want{err: "priceAdjustment and price are mutually exclusive"},
+		},
+		"Neither": {
+			reason:  "An overlay should set a price or a price adjustment.",
+			overlay: v1beta1.NodeOverlay{Families: []string{"m6i"}},
+			want:    want{err: "one of priceAdjustment and price must be set"},
+		},
+		"UnsignedAdjustment": {
+			reason:  "A price adjustment should be signed.",
+			overlay: v1beta1.NodeOverlay{PriceAdjustment: "15%"},
+			want:    want{err: `priceAdjustment "15%" must be a signed amount, e.g. -0.02, or percentage, e.g. -15%`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec, err := nodeOverlaySpec(tc.overlay, base, "karpenter.k8s.aws/instance-family")
+			got := want{spec: spec}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nnodeOverlaySpec(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
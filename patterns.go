@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path"
+	"slices"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/response"
+	corev1 "k8s.io/api/core/v1"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// instanceTypePatterns returns the patterns that allow and deny instance types
+// to XRs of the supplied environment: the filter's patterns and those of the
+// environment. It returns an error if any pattern is malformed.
+func instanceTypePatterns(f *v1beta1.InstanceTypeFilter, environment string) (allow, deny []string, err error) {
+	env := f.Environments[environment]
+	allow = append(slices.Clone(f.Allow), env.Allow...)
+	deny = append(slices.Clone(f.Deny), env.Deny...)
+	for _, p := range slices.Concat(allow, deny) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid instance type pattern %q", p)
+		}
+	}
+	return allow, deny, nil
+}
+
+// matchesAny returns true if the supplied instance type matches any of the
+// supplied well-formed patterns.
+func matchesAny(instanceType string, patterns []string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool {
+		ok, _ := path.Match(p, instanceType)
+		return ok
+	})
+}
+
+// An instanceTypeSelection is what allow and deny patterns leave of the
+// instance types of a NodePool's categories.
+type instanceTypeSelection struct {
+	// Offered is how many instance types of the categories are offered.
+	Offered int
+
+	// Allowed are the offered instance types the patterns allow.
+	Allowed []string
+
+	// Categories are the categories with any allowed instance types.
+	Categories []string
+
+	// ByCategory is true if the patterns allow every offered instance type of
+	// the Categories, so the Categories alone select the Allowed instance
+	// types.
+	ByCategory bool
+}
+
+// filterInstanceTypes returns what the supplied filter leaves of the supplied
+// offered instance types of the supplied categories for XRs of the supplied
+// environment. It returns nil, and warns, if the supplied autoscaler doesn't
+// compose NodePools the filter could constrain.
+func filterInstanceTypes(rsp *fnv1.RunFunctionResponse, filter *v1beta1.InstanceTypeFilter, prov nodeProvider, autoscaler, environment, region string, offered, categories []string) (*instanceTypeSelection, error) {
+	if !composesNodePool(autoscaler) {
+		response.Warning(rsp, errors.Errorf("the Input's instanceTypes don't constrain the instance types of autoscaler %q", autoscaler)).TargetCompositeAndClaim()
+		return nil, nil
+	}
+	if _, ok := prov.(instanceTypeLister); !ok {
+		return nil, errors.Errorf("the Input's instanceTypes require provider %q", providerAWS)
+	}
+	allow, deny, err := instanceTypePatterns(filter, environment)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid instanceTypes in the Input")
+	}
+	sel := selectInstanceTypes(offered, categories, allow, deny)
+	if len(sel.Allowed) == 0 {
+		return nil, errors.Errorf("the Input's instanceTypes allow none of the %d instance types of categories %v offered in %s", sel.Offered, categories, region)
+	}
+	response.Normalf(rsp, "%d of %d instance types of categories %v offered in %s remain after the Input's instanceTypes", len(sel.Allowed), sel.Offered, categories, region)
+	return &sel, nil
+}
+
+// selectInstanceTypes returns what the supplied allow and deny patterns leave
+// of the supplied offered instance types of the supplied categories. Deny
+// patterns take precedence over allow patterns.
+func selectInstanceTypes(offered, categories, allow, deny []string) instanceTypeSelection {
+	sel := instanceTypeSelection{ByCategory: true}
+	allowedIn := map[string]int{}
+	offeredIn := map[string]int{}
+	for _, t := range offered {
+		c := instanceCategory(t)
+		if !slices.Contains(categories, c) {
+			continue
+		}
+		sel.Offered++
+		offeredIn[c]++
+		if (len(allow) > 0 && !matchesAny(t, allow)) || matchesAny(t, deny) {
+			continue
+		}
+		allowedIn[c]++
+		sel.Allowed = append(sel.Allowed, t)
+	}
+	for _, c := range categories {
+		if allowedIn[c] == 0 {
+			continue
+		}
+		sel.Categories = append(sel.Categories, c)
+		if allowedIn[c] < offeredIn[c] {
+			sel.ByCategory = false
+		}
+	}
+	return sel
+}
+
+// instanceTypeRequirement constrains a NodePool to the supplied instance types.
+func instanceTypeRequirement(instanceTypes []string) karpenterv1.NodeSelectorRequirementWithMinValues {
+	r := karpenterv1.NodeSelectorRequirementWithMinValues{}
+	r.Key = corev1.LabelInstanceTypeStable
+	r.Operator = corev1.NodeSelectorOpIn
+	r.Values = instanceTypes
+	return r
+}
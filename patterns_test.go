@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestInstanceTypePatterns(t *testing.T) {
+	f := &v1beta1.InstanceTypeFilter{
+		InstanceTypePatterns: v1beta1.InstanceTypePatterns{Allow: []string{"m*", "c*"}, Deny: []string{"*.metal"}},
+		Environments: map[string]v1beta1.InstanceTypePatterns{
+			"production": {Deny: []string{"t*"}},
+			"broken":     {Deny: []string{"[m"}},
+		},
+	}
+
+	type want struct {
+		allow []string
+		deny  []string
+		err   string
+	}
+	cases := map[string]struct {
+		reason      string
+		environment string
+		want        want
+	}{
+		"Default": {
+			reason:      "XRs of environments without patterns should use the filter's patterns.",
+			environment: "development",
+			want:        want{allow: []string{"m*", "c*"}, deny: []string{"*.metal"}},
+		},
+		"Environment": {
+			reason:      "XRs should add their environment's patterns to the filter's.",
+			environment: "production",
+			want:        want{allow: []string{"m*", "c*"}, deny: []string{"*.metal", "t*"}},
+		},
+		"Malformed": {
+			reason:      "Malformed patterns should be an error.",
+			environment: "broken",
+			want:        want{err: `invalid instance type pattern "[m": syntax error in pattern`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			allow, deny, err := instanceTypePatterns(f, tc.environment)
+			got := want{allow: allow, deny: deny}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ninstanceTypePatterns(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSelectInstanceTypes(t *testing.T) {
+	offered := []string{"c5.large", "c8g.16xlarge", "m5.large", "m5.metal", "r6i.large", "t3.micro"}
+
+	cases := map[string]struct {
+		reason      string
+		categories  []string
+		allow, deny []string
+		want        instanceTypeSelection
+	}{
+		"Unfiltered": {
+			reason:     "Without patterns every offered instance type of the categories should be allowed, by category.",
+			categories: []string{"m", "c"},
+			want: instanceTypeSelection{
+				Offered:    4,
+				Allowed:    []string{"c5.large", "c8g.16xlarge", "m5.large", "m5.metal"},
+				Categories: []string{"m", "c"},
+				ByCategory: true,
+			},
+		},
+		"WholeCategory": {
+			reason:     "Patterns that deny every instance type of a category should drop the category.",
+			categories: []string{"m", "c", "t"},
+			deny:       []string{"t*"},
+			want: instanceTypeSelection{
+				Offered:    5,
+				Allowed:    []string{"c5.large", "c8g.16xlarge", "m5.large", "m5.metal"},
+				Categories: []string{"m", "c"},
+				ByCategory: true,
+			},
+		},
+		"PartialCategory": {
+			reason:     "Patterns that deny some instance types of a category can't be expressed as categories.",
+			categories: []string{"m", "c"},
+			deny:       []string{"*.metal"},
+			want: instanceTypeSelection{
+				Offered:    4,
+				Allowed:    []string{"c5.large", "c8g.16xlarge", "m5.large"},
+				Categories: []string{"m", "c"},
+			},
+		},
+		"DenyOverridesAllow": {
+			reason:     "Deny patterns should take precedence over allow patterns.",
+			categories: []string{"m", "c"},
+			allow:      []string{"m*", "c8g.*"},
+			deny:       []string{"*.metal"},
+			want: instanceTypeSelection{
+				Offered:    4,
+				Allowed:    []string{"c8g.16xlarge", "m5.large"},
+				Categories: []string{"m", "c"},
+			},
+		},
+		"NoneAllowed": {
+			reason:     "Patterns may deny every instance type.",
+			categories: []string{"r"},
+			allow:      []string{"m*"},
+			want:       instanceTypeSelection{Offered: 1, ByCategory: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := selectInstanceTypes(offered, tc.categories, tc.allow, tc.deny)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nselectInstanceTypes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
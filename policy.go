@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// extraResourcePolicy is the key under which the Function requests the
+// ConfigMap holding policy rules.
+const extraResourcePolicy = "policy"
+
+// policyRule is a compiled policy rule.
+type policyRule struct {
+	v1beta1.PolicyRule
+
+	program cel.Program
+}
+
+// policy is a set of compiled policy rules.
+type policy []policyRule
+
+// loadPolicy compiles the supplied policy's rules, and those of the ConfigMap
+// it references. It requires the ConfigMap as an extra resource, and returns
+// false if Crossplane hasn't fetched it yet. It returns an empty policy if p
+// is nil.
+func (f *Function) loadPolicy(rsp *fnv1.RunFunctionResponse, p *v1beta1.Policy, extra map[string][]resource.Extra) (policy, bool, error) {
+	if p == nil {
+		return nil, true, nil
+	}
+	rules, ready := p.Rules, true
+	if ref := p.ConfigMapRef; ref != nil {
+		requireExtraResource(rsp, extraResourcePolicy, &fnv1.ResourceSelector{
+			ApiVersion: "v1",
+			Kind:       "ConfigMap",
+			Match:      &fnv1.ResourceSelector_MatchName{MatchName: ref.Name},
+		})
+		cms, ok := extra[extraResourcePolicy]
+		switch {
+		case !ok:
+			f.log.Debug("Waiting for policy ConfigMap", "name", ref.Name)
+			ready = false
+		case len(cms) == 0:
+			return nil, false, errors.Errorf("cannot find policy ConfigMap %q", ref.Name)
+		default:
+			cmRules, err := policyRulesFromConfigMap(cms[0])
+			if err != nil {
+				return nil, false, err
+			}
+			rules = append(rules[:len(rules):len(rules)], cmRules...)
+		}
+	}
+	pol, err := compilePolicy(rules)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "invalid policy")
+	}
+	return pol, ready, nil
+}
+
+// newPolicyEnv returns the CEL environment policy rules are compiled in.
+func newPolicyEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("nodePool", cel.DynType),
+		cel.Variable("xr", cel.DynType),
+		cel.Variable("environment", cel.StringType),
+	)
+}
+
+// compilePolicy compiles the supplied rules. It returns an error naming every
+// rule that doesn't compile to a boolean expression.
+func compilePolicy(rules []v1beta1.PolicyRule) (policy, error) {
+	env, err := newPolicyEnv()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create CEL environment")
+	}
+
+	p := make(policy, 0, len(rules))
+	var errs []error
+	for _, r := range rules {
+		ast, iss := env.Compile(r.Expression)
+		if iss.Err() != nil {
+			errs = append(errs, errors.Wrapf(iss.Err(), "cannot compile policy rule %q", r.Name))
+			continue
+		}
+		if !ast.OutputType().IsExactType(types.BoolType) && !ast.OutputType().IsExactType(types.DynType) {
+			errs = append(errs, errors.Errorf("policy rule %q must evaluate to a bool, not %s", r.Name, ast.OutputType()))
+			continue
+		}
+		prg, err := env.Program(ast)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "cannot create program for policy rule %q", r.Name))
+			continue
+		}
+		p = append(p, policyRule{PolicyRule: r, program: prg})
+	}
+	return p, errors.Join(errs...)
+}
+
+// Evaluate the policy against the supplied NodePool and XR. It returns a
+// description of every rule the NodePool violates. Rules that can't be
+// evaluated are treated as violated.
+func (p policy) Evaluate(nodePool, xr map[string]any, environment string) []string {
+	vars := map[string]any{
+		"nodePool":    nodePool,
+		"xr":          xr,
+		"environment": environment,
+	}
+
+	var violations []string
+	for _, r := range p {
+		out, _, err := r.program.Eval(vars)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s: cannot evaluate: %s", r.Name, err))
+			continue
+		}
+		ok, isBool := out.Value().(bool)
+		switch {
+		case !isBool:
+			violations = append(violations, fmt.Sprintf("%s: evaluated to %v, not a bool", r.Name, out.Value()))
+		case !ok && r.Message != "":
+			violations = append(violations, fmt.Sprintf("%s: %s", r.Name, r.Message))
+		case !ok:
+			violations = append(violations, fmt.Sprintf("%s: %s evaluated to false", r.Name, r.Expression))
+		}
+	}
+	return violations
+}
+
+// policyRulesFromConfigMap returns the policy rules held by the supplied
+// ConfigMap, ordered by name.
+func policyRulesFromConfigMap(cm resource.Extra) ([]v1beta1.PolicyRule, error) {
+	data, _, err := unstructured.NestedStringMap(cm.Resource.Object, "data")
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read data of ConfigMap %q", cm.Resource.GetName())
+	}
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make([]v1beta1.PolicyRule, 0, len(names))
+	for _, name := range names {
+		rules = append(rules, v1beta1.PolicyRule{Name: name, Expression: data[name]})
+	}
+	return rules, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	nodePool := map[string]any{
+		"metadata": map[string]any{"name": "np1"},
+		"spec": map[string]any{
+			"limits": map[string]any{"cpu": "2"},
+		},
+	}
+	xr := map[string]any{
+		"metadata": map[string]any{"name": "np1"},
+	}
+
+	cases := map[string]struct {
+		reason string
+		rules  []v1beta1.PolicyRule
+		want   []string
+	}{
+		"Compliant": {
+			reason: "A NodePool that satisfies every rule should have no violations",
+			rules: []v1beta1.PolicyRule{
+				{Name: "named-after-xr", Expression: "nodePool.metadata.name == xr.metadata.name"},
+				{Name: "development", Expression: "environment == 'development'"},
+			},
+		},
+		"Violated": {
+			reason: "A violated rule should be described by its message if it has one",
+			rules: []v1beta1.PolicyRule{
+				{Name: "small", Expression: "nodePool.spec.limits.cpu == '1'", Message: "NodePools may use at most one CPU"},
+				{Name: "production", Expression: "environment == 'production'"},
+			},
+			want: []string{
+				"small: NodePools may use at most one CPU",
+				"production: environment == 'production' evaluated to false",
+			},
+		},
+		"NotABool": {
+			reason: "A dynamically typed rule that doesn't evaluate to a bool should be treated as violated",
+			rules: []v1beta1.PolicyRule{
+				{Name: "cpu", Expression: "nodePool.spec.limits.cpu"},
+			},
+			want: []string{"cpu: evaluated to 2, not a bool"},
+		},
+		"CannotEvaluate": {
+			reason: "A rule that fails to evaluate should be treated as violated",
+			rules: []v1beta1.PolicyRule{
+				{Name: "gpu", Expression: "nodePool.spec.limits.gpu == '0'"},
+			},
+			want: []string{"gpu: cannot evaluate: no such key: gpu"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := compilePolicy(tc.rules)
+			if err != nil {
+				t.Fatalf("compilePolicy(...): %v", err)
+			}
+			got := p.Evaluate(nodePool, xr, "development")
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\np.Evaluate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCompilePolicy(t *testing.T) {
+	_, err := compilePolicy([]v1beta1.PolicyRule{
+		{Name: "ok", Expression: "environment == 'production'"},
+		{Name: "string", Expression: "environment"},
+		{Name: "broken", Expression: "environment =="},
+	})
+	if err == nil {
+		t.Fatal("compilePolicy(...): want error, got nil")
+	}
+	for _, want := range []string{`policy rule "string" must evaluate to a bool`, `cannot compile policy rule "broken"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("compilePolicy(...): want error containing %q, got %q", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), `"ok"`) {
+		t.Errorf("compilePolicy(...): want no error for rule \"ok\", got %q", err)
+	}
+}
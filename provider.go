@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/logging"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+)
+
+// Karpenter cloud providers an XR may select with spec.Provider.
+const (
+	providerAWS   = "aws"
+	providerAzure = "azure"
+)
+
+// A nodeProvider composes the parts of a NodePool that depend on the
+// Karpenter cloud provider the NodePool's nodes run on.
+type nodeProvider interface {
+	// RegionField is the XR field that holds the region nodes run in.
+	RegionField() string
+
+	// ValidateRegion returns an error if the provider doesn't know the region.
+	ValidateRegion(ctx context.Context, region string) error
+
+	// Offerings returns the names of the instance types offered in the
+	// supplied region, ordered by name.
+	Offerings(ctx context.Context, region string) ([]string, error)
+
+	// Categories returns the instance categories the NodePool should use in
+	// the supplied region, given the instance types offered there.
+	Categories(region string, offered []string) []string
+
+	// CategoryLabel is the well-known node label of an instance's category.
+	CategoryLabel() string
+
+	// FamilyLabel is the well-known node label of an instance's family.
+	FamilyLabel() string
+
+	// NodeClassRef returns the NodeClass the NodePool's nodes are configured by.
+	NodeClassRef() *karpenterv1.NodeClassReference
+
+	// PricingCategory returns the instance category whose pricing
+	// approximates the supplied category, for budgeting.
+	PricingCategory(category string) string
+}
+
+// provider returns the nodeProvider with the supplied name. An empty name
+// selects AWS.
+func (f *Function) provider(name string) (nodeProvider, error) {
+	switch name {
+	case providerAWS, "":
+		newClient := f.ec2
+		if newClient == nil {
+			newClient = awsEC2("")
+		}
+		return &awsProvider{log: f.log, ec2: newClient}, nil
+	case providerAzure:
+		if f.azure == nil {
+			return nil, errors.New("no Azure SKU catalog is configured")
+		}
+		return &azureProvider{log: f.log, skus: f.azure}, nil
+	default:
+		return nil, errors.Errorf("unknown provider %q, must be %q or %q", name, providerAWS, providerAzure)
+	}
+}
+
+// awsCheckInstanceType is the instance type that must be offered in a region
+// for AWS NodePools to use the c category.
+const awsCheckInstanceType = "c8g.16xlarge"
+
+// awsProvider composes NodePools for Karpenter's AWS provider, using the EC2
+// API to discover what is offered in a region.
+type awsProvider struct {
+	log logging.Logger
+	ec2 func(ctx context.Context, region string) (ec2API, error)
+
+	// clients are the EC2 clients created for each region.
+	clients map[string]ec2API
+}
+
+// client returns the EC2 client for the supplied region, creating it the
+// first time the region is used.
+func (p *awsProvider) client(ctx context.Context, region string) (ec2API, error) {
+	if c, ok := p.clients[region]; ok {
+		return c, nil
+	}
+	c, err := p.ec2(ctx, region)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load SDK config")
+	}
+	if p.clients == nil {
+		p.clients = map[string]ec2API{}
+	}
+	p.clients[region] = c
+	return c, nil
+}
+
+// RegionField is spec.AwsRegion.
+func (p *awsProvider) RegionField() string { return "spec.AwsRegion" }
+
+// ValidateRegion returns an error if the region isn't in any AWS partition.
+func (p *awsProvider) ValidateRegion(ctx context.Context, region string) error {
+	return p.checkRegion(ctx, region)
+}
+
+// Offerings returns the names of the instance types offered in the supplied
+// region, ordered by name.
+func (p *awsProvider) Offerings(ctx context.Context, region string) ([]string, error) {
+	ec2Client, err := p.client(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	offerings, err := describeInstanceTypeOfferings(ctx, ec2Client, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeRegion,
+		Filters:      []types.Filter{{Name: aws.String("location"), Values: []string{region}}},
+	})
+	if err != nil {
+		p.log.Info("unable to describe instance type offerings")
+		return nil, errors.Wrap(err, "unable to describe instance type offerings")
+	}
+	names := make([]string, 0, len(offerings.InstanceTypeOfferings))
+	for _, o := range offerings.InstanceTypeOfferings {
+		names = append(names, string(o.InstanceType))
+	}
+	sort.Strings(names)
+	return slices.Compact(names), nil
+}
+
+// Categories returns the m category, plus the c category if
+// awsCheckInstanceType is offered in the region.
+func (p *awsProvider) Categories(region string, offered []string) []string {
+	categories := []string{"m"}
+	if slices.Contains(offered, awsCheckInstanceType) {
+		p.log.Info(awsCheckInstanceType + " instance type is available in " + region)
+		categories = append(categories, "c")
+	} else {
+		p.log.Info(awsCheckInstanceType + " instance type is not available in " + region + ", using default")
+	}
+	return categories
+}
+
+// CategoryLabel is karpenter.k8s.aws/instance-category.
+func (p *awsProvider) CategoryLabel() string { return "karpenter.k8s.aws/instance-category" }
+
+// FamilyLabel is karpenter.k8s.aws/instance-family.
+func (p *awsProvider) FamilyLabel() string { return "karpenter.k8s.aws/instance-family" }
+
+// NodeClassRef returns the default2 EC2NodeClass.
+func (p *awsProvider) NodeClassRef() *karpenterv1.NodeClassReference {
+	return &karpenterv1.NodeClassReference{
+		Group: "karpenter.sh",
+		Kind:  "EC2NodeClass",
+		Name:  "default2",
+	}
+}
+
+// PricingCategory returns the category unchanged; budget pricing is AWS
+// pricing.
+func (p *awsProvider) PricingCategory(category string) string { return category }
+
+// azureCheckSKU is the VM SKU that must be available in a location for Azure
+// NodePools to use the F family.
+const azureCheckSKU = "Standard_F16s_v2"
+
+// azurePricingCategories maps Azure SKU families to the AWS instance category
+// with similar vCPU to memory ratio and pricing.
+var azurePricingCategories = map[string]string{
+	"B": "t",
+	"D": "m",
+	"E": "r",
+	"F": "c",
+}
+
+// azureProvider composes NodePools for Karpenter's Azure provider, using an
+// offline catalog of the VM SKUs available in each location.
+type azureProvider struct {
+	log  logging.Logger
+	skus *catalog
+}
+
+// RegionField is spec.AzureLocation.
+func (p *azureProvider) RegionField() string { return "spec.AzureLocation" }
+
+// ValidateRegion returns an error if the location isn't in the SKU catalog.
+func (p *azureProvider) ValidateRegion(_ context.Context, location string) error {
+	if location == "" {
+		return errors.New("Azure location is empty")
+	}
+	if _, ok := p.skus.Locations[location]; !ok {
+		return errors.Errorf("Azure location %q is not in the SKU catalog", location)
+	}
+	return nil
+}
+
+// Offerings returns the VM SKUs the catalog lists for the supplied location,
+// ordered by name.
+func (p *azureProvider) Offerings(_ context.Context, location string) ([]string, error) {
+	skus := slices.Clone(p.skus.Locations[location].SKUs)
+	sort.Strings(skus)
+	return skus, nil
+}
+
+// Categories returns the D family, plus the F family if azureCheckSKU is
+// available in the location.
+func (p *azureProvider) Categories(location string, offered []string) []string {
+	categories := []string{"D"}
+	if slices.Contains(offered, azureCheckSKU) {
+		p.log.Info(azureCheckSKU + " SKU is available in " + location)
+		return append(categories, "F")
+	}
+	p.log.Info(azureCheckSKU + " SKU is not available in " + location + ", using default")
+	return categories
+}
+
+// CategoryLabel is karpenter.azure.com/sku-family.
+func (p *azureProvider) CategoryLabel() string { return "karpenter.azure.com/sku-family" }
+
+// FamilyLabel is karpenter.azure.com/sku-family. Azure SKU families are the
+// categories NodePools select.
+func (p *azureProvider) FamilyLabel() string { return "karpenter.azure.com/sku-family" }
+
+// NodeClassRef returns the default AKSNodeClass.
+func (p *azureProvider) NodeClassRef() *karpenterv1.NodeClassReference {
+	return &karpenterv1.NodeClassReference{
+		Group: "karpenter.azure.com",
+		Kind:  "AKSNodeClass",
+		Name:  "default",
+	}
+}
+
+// PricingCategory returns the AWS instance category similar to the supplied
+// SKU family.
+func (p *azureProvider) PricingCategory(family string) string {
+	return azurePricingCategories[family]
+}
+
+// skuFamily returns the Karpenter SKU family of the supplied Azure VM SKU,
+// e.g. F for Standard_F16s_v2.
+func skuFamily(sku string) string {
+	size := strings.TrimPrefix(sku, "Standard_")
+	if i := strings.IndexFunc(size, unicode.IsDigit); i >= 0 {
+		return size[:i]
+	}
+	return size
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSKUFamily(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		sku    string
+		want   string
+	}{
+		"GeneralPurpose": {
+			reason: "The family of a D series SKU should be D.",
+			sku:    "Standard_D4s_v5",
+			want:   "D",
+		},
+		"ComputeOptimized": {
+			reason: "The family of an F series SKU should be F.",
+			sku:    "Standard_F16s_v2",
+			want:   "F",
+		},
+		"MultiLetter": {
+			reason: "The family of a GPU SKU should include every letter before the size.",
+			sku:    "Standard_NC24ads_A100_v4",
+			want:   "NC",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, skuFamily(tc.sku)); diff != "" {
+				t.Errorf("\n%s\nskuFamily(%q): -want, +got:\n%s", tc.reason, tc.sku, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// maxRegionSuggestionDistance is the largest edit distance between an unknown
+// region and a known region for the Function to suggest the known region.
+const maxRegionSuggestionDistance = 2
+
+// A partition is a group of AWS regions that share endpoints, credentials and
+// ARN format.
+type partition struct {
+	// ID of the partition, as used in ARNs, e.g. aws-us-gov.
+	ID string
+
+	// Regions in the partition that the Function knows about.
+	Regions []string
+
+	// RegionRegex matches the names of the partition's regions, including
+	// those opened after the Function's regions were listed.
+	RegionRegex *regexp.Regexp
+
+	// GlobalRegion is the region that serves the partition's global
+	// endpoints, and knows every region in the partition.
+	GlobalRegion string
+}
+
+// partitions are the AWS partitions, and the regions in them, per the AWS
+// SDK's partition metadata.
+var partitions = []partition{
+	{
+		ID: "aws",
+		Regions: []string{
+			"af-south-1",
+			"ap-east-1", "ap-east-2",
+			"ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+			"ap-south-1", "ap-south-2",
+			"ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-4", "ap-southeast-5", "ap-southeast-6", "ap-southeast-7",
+			"ca-central-1", "ca-west-1",
+			"eu-central-1", "eu-central-2",
+			"eu-north-1",
+			"eu-south-1", "eu-south-2",
+			"eu-west-1", "eu-west-2", "eu-west-3",
+			"il-central-1",
+			"me-central-1", "me-south-1",
+			"mx-central-1",
+			"sa-east-1",
+			"us-east-1", "us-east-2",
+			"us-west-1", "us-west-2",
+		},
+		RegionRegex:  regexp.MustCompile(`^(us|eu|ap|sa|ca|me|af|il|mx)\-\w+\-\d+$`),
+		GlobalRegion: "us-east-1",
+	},
+	{
+		ID:           "aws-cn",
+		Regions:      []string{"cn-north-1", "cn-northwest-1"},
+		RegionRegex:  regexp.MustCompile(`^cn\-\w+\-\d+$`),
+		GlobalRegion: "cn-northwest-1",
+	},
+	{
+		ID:           "aws-eusc",
+		Regions:      []string{"eusc-de-east-1"},
+		RegionRegex:  regexp.MustCompile(`^eusc\-(de)\-\w+\-\d+$`),
+		GlobalRegion: "eusc-de-east-1",
+	},
+	{
+		ID:           "aws-iso",
+		Regions:      []string{"us-iso-east-1", "us-iso-west-1"},
+		RegionRegex:  regexp.MustCompile(`^us\-iso\-\w+\-\d+$`),
+		GlobalRegion: "us-iso-east-1",
+	},
+	{
+		ID:           "aws-iso-b",
+		Regions:      []string{"us-isob-east-1"},
+		RegionRegex:  regexp.MustCompile(`^us\-isob\-\w+\-\d+$`),
+		GlobalRegion: "us-isob-east-1",
+	},
+	{
+		ID:           "aws-iso-e",
+		Regions:      []string{"eu-isoe-west-1"},
+		RegionRegex:  regexp.MustCompile(`^eu\-isoe\-\w+\-\d+$`),
+		GlobalRegion: "eu-isoe-west-1",
+	},
+	{
+		ID:           "aws-iso-f",
+		Regions:      []string{"us-isof-east-1", "us-isof-south-1"},
+		RegionRegex:  regexp.MustCompile(`^us\-isof\-\w+\-\d+$`),
+		GlobalRegion: "us-isof-south-1",
+	},
+	{
+		ID:           "aws-us-gov",
+		Regions:      []string{"us-gov-east-1", "us-gov-west-1"},
+		RegionRegex:  regexp.MustCompile(`^us\-gov\-\w+\-\d+$`),
+		GlobalRegion: "us-gov-west-1",
+	},
+}
+
+// partitionFor returns the partition of the supplied region: the partition
+// that lists it, or else the one whose region names it matches. A region may
+// match a partition yet not exist; see checkRegion.
+func partitionFor(region string) (partition, error) {
+	if region == "" {
+		return partition{}, errors.New("AWS region is empty")
+	}
+	for _, p := range partitions {
+		if slices.Contains(p.Regions, region) {
+			return p, nil
+		}
+	}
+	for _, p := range partitions {
+		if p.RegionRegex.MatchString(region) {
+			return p, nil
+		}
+	}
+	return partition{}, &unknownRegionError{Region: region, Suggestion: closestRegion(region, knownRegions())}
+}
+
+// knownRegions returns the regions of every partition.
+func knownRegions() []string {
+	var known []string
+	for _, p := range partitions {
+		known = append(known, p.Regions...)
+	}
+	return known
+}
+
+// An unknownRegionError is returned for a region that isn't in any AWS
+// partition.
+type unknownRegionError struct {
+	// Region that is unknown.
+	Region string
+
+	// Suggestion is the known region closest to Region, if any is close.
+	Suggestion string
+}
+
+func (e *unknownRegionError) Error() string {
+	if e.Suggestion == "" {
+		return fmt.Sprintf("unknown AWS region %q", e.Region)
+	}
+	return fmt.Sprintf("unknown AWS region %q, did you mean %q?", e.Region, e.Suggestion)
+}
+
+// closestRegion returns the supplied known region closest to the supplied
+// region, or "" if none is within maxRegionSuggestionDistance.
+func closestRegion(region string, known []string) string {
+	var suggestions []string
+	best := maxRegionSuggestionDistance + 1
+	for _, r := range known {
+		d := editDistance(region, r)
+		switch {
+		case d < best:
+			best = d
+			suggestions = []string{r}
+		case d == best:
+			suggestions = append(suggestions, r)
+		}
+	}
+	if len(suggestions) == 0 {
+		return ""
+	}
+	sort.Strings(suggestions)
+	return suggestions[0]
+}
+
+// checkRegion returns an unknownRegionError if the supplied region isn't in
+// any partition. Regions the Function doesn't list may have opened since, so
+// it asks the EC2 API of their partition's global region about them. It
+// treats them as unknown if it can't ask, for example because the EC2 API is
+// an offline catalog.
+func (p *awsProvider) checkRegion(ctx context.Context, region string) error {
+	part, err := partitionFor(region)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(part.Regions, region) {
+		return nil
+	}
+	known := knownRegions()
+	described, err := p.describeRegions(ctx, part.GlobalRegion)
+	if err != nil {
+		p.log.Debug("Cannot describe regions", "partition", part.ID, "error", err)
+	}
+	if slices.Contains(described, region) {
+		return nil
+	}
+	return &unknownRegionError{Region: region, Suggestion: closestRegion(region, append(known, described...))}
+}
+
+// describeRegions returns the names of the regions the EC2 API of the
+// supplied region knows about, including those not enabled for the account.
+func (p *awsProvider) describeRegions(ctx context.Context, region string) ([]string, error) {
+	c, err := p.client(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot describe regions")
+	}
+	names := make([]string, 0, len(out.Regions))
+	for _, r := range out.Regions {
+		names = append(names, aws.ToString(r.RegionName))
+	}
+	return names, nil
+}
+
+// checkARNPartition returns an error if the supplied ARN isn't in the
+// partition of the supplied region. IAM roles can't be assumed across
+// partitions.
+func checkARNPartition(a, region string) error {
+	parsed, err := arn.Parse(a)
+	if err != nil {
+		return errors.Wrapf(err, "invalid ARN %q", a)
+	}
+	part, err := partitionFor(region)
+	if err != nil {
+		return err
+	}
+	if parsed.Partition != part.ID {
+		return errors.Errorf("ARN %q is in partition %q, but region %q is in partition %q", a, parsed.Partition, region, part.ID)
+	}
+	return nil
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPartitionFor(t *testing.T) {
+	type want struct {
+		partition string
+		err       string
+	}
+
+	cases := map[string]struct {
+		reason string
+		region string
+		want   want
+	}{
+		"Commercial": {
+			reason: "A commercial region should be in the aws partition.",
+			region: "us-east-1",
+			want:   want{partition: "aws"},
+		},
+		"China": {
+			reason: "A China region should be in the aws-cn partition.",
+			region: "cn-northwest-1",
+			want:   want{partition: "aws-cn"},
+		},
+		"GovCloud": {
+			reason: "A GovCloud region should be in the aws-us-gov partition.",
+			region: "us-gov-west-1",
+			want:   want{partition: "aws-us-gov"},
+		},
+		"ISOB": {
+			reason: "An ISOB region should be in the aws-iso-b partition, not the aws-iso or aws partitions.",
+			region: "us-isob-east-1",
+			want:   want{partition: "aws-iso-b"},
+		},
+		"Unlisted": {
+			reason: "A region named like a partition's regions but not listed should be in the partition; whether it exists is for checkRegion to say.",
+			region: "us-est-1",
+			want:   want{partition: "aws"},
+		},
+		"Malformed": {
+			reason: "A region not named like any partition's regions should return an error.",
+			region: "us-east1",
+			want:   want{err: `unknown AWS region "us-east1", did you mean "us-east-1"?`},
+		},
+		"Unknown": {
+			reason: "A region unlike any partition's regions should return an error.",
+			region: "moon-base-1",
+			want:   want{err: `unknown AWS region "moon-base-1"`},
+		},
+		"Empty": {
+			reason: "An empty region should return an error.",
+			region: "",
+			want:   want{err: "AWS region is empty"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := partitionFor(tc.region)
+			got := want{partition: p.ID}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\npartitionFor(%q): -want, +got:\n%s", tc.reason, tc.region, diff)
+			}
+		})
+	}
+}
+
+// regionsEC2 is an EC2 API that describes the supplied regions, or returns the
+// supplied error.
+type regionsEC2 struct {
+	ec2API
+	regions []string
+	err     error
+	calls   *int
+}
+
+func (c regionsEC2) DescribeRegions(_ context.Context, _ *ec2.DescribeRegionsInput, _ ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	*c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	out := &ec2.DescribeRegionsOutput{}
+	for _, r := range c.regions {
+		out.Regions = append(out.Regions, types.Region{RegionName: aws.String(r)})
+	}
+	return out, nil
+}
+
+func TestCheckRegion(t *testing.T) {
+	type want struct {
+		err   string
+		calls int
+	}
+
+	cases := map[string]struct {
+		reason  string
+		region  string
+		regions []string
+		err     error
+		want    want
+	}{
+		"Listed": {
+			reason: "A listed region should be known without asking the EC2 API.",
+			region: "eu-west-1",
+		},
+		"NewRegion": {
+			reason:  "An unlisted region the partition's EC2 API knows should be known.",
+			region:  "ap-southeast-9",
+			regions: []string{"us-east-1", "ap-southeast-9"},
+			want:    want{calls: 1},
+		},
+		"Misspelled": {
+			reason:  "An unlisted region the partition's EC2 API doesn't know should be unknown, suggesting the closest region.",
+			region:  "us-est-1",
+			regions: []string{"us-east-1", "ap-southeast-9"},
+			want:    want{err: `unknown AWS region "us-est-1", did you mean "us-east-1"?`, calls: 1},
+		},
+		"Offline": {
+			reason: "An unlisted region should be unknown if the partition's EC2 API can't be asked, as when it's an offline catalog.",
+			region: "us-est-1",
+			err:    errors.New("the offerings catalog doesn't describe regions"),
+			want:   want{err: `unknown AWS region "us-est-1", did you mean "us-east-1"?`, calls: 1},
+		},
+		"NoPartition": {
+			reason: "A region not named like any partition's regions should be unknown without asking the EC2 API.",
+			region: "moon-base-1",
+			want:   want{err: `unknown AWS region "moon-base-1"`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			p := &awsProvider{log: logging.NewNopLogger(), ec2: func(_ context.Context, _ string) (ec2API, error) {
+				return regionsEC2{regions: tc.regions, err: tc.err, calls: &got.calls}, nil
+			}}
+			if err := p.checkRegion(context.Background(), tc.region); err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ncheckRegion(%q): -want, +got:\n%s", tc.reason, tc.region, diff)
+			}
+		})
+	}
+}
+
+func TestClosestRegion(t *testing.T) {
+	known := []string{"af-south-1", "us-east-1", "us-east-2", "us-west-1"}
+
+	cases := map[string]struct {
+		reason string
+		region string
+		want   string
+	}{
+		"Typo": {
+			reason: "A misspelled region should suggest the closest known region.",
+			region: "us-est-1",
+			want:   "us-east-1",
+		},
+		"Tie": {
+			reason: "Equally close known regions should suggest the first by name.",
+			region: "us-east-3",
+			want:   "us-east-1",
+		},
+		"Distant": {
+			reason: "A region unlike any known region should suggest nothing.",
+			region: "moon-base-1",
+			want:   "",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, closestRegion(tc.region, known)); diff != "" {
+				t.Errorf("\n%s\nclosestRegion(%q, ...): -want, +got:\n%s", tc.reason, tc.region, diff)
+			}
+		})
+	}
+}
+
+func TestCheckARNPartition(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		arn    string
+		region string
+		want   string
+	}{
+		"SamePartition": {
+			reason: "An ARN in the region's partition should be valid.",
+			arn:    "arn:aws-us-gov:iam::123456789012:role/nodes",
+			region: "us-gov-west-1",
+		},
+		"OtherPartition": {
+			reason: "An ARN in another partition than the region's should be an error.",
+			arn:    "arn:aws:iam::123456789012:role/nodes",
+			region: "cn-north-1",
+			want:   `ARN "arn:aws:iam::123456789012:role/nodes" is in partition "aws", but region "cn-north-1" is in partition "aws-cn"`,
+		},
+		"Malformed": {
+			reason: "A malformed ARN should be an error.",
+			arn:    "nodes",
+			region: "us-east-1",
+			want:   `invalid ARN "nodes": arn: invalid prefix`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			if err := checkARNPartition(tc.arn, tc.region); err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckARNPartition(%q, %q): -want, +got:\n%s", tc.reason, tc.arn, tc.region, diff)
+			}
+		})
+	}
+}
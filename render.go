@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/crossplane/function-sdk-go"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// annotationCompositionResourceName is the annotation Crossplane uses to record
+// the name of a composed resource within its composition.
+const annotationCompositionResourceName = "crossplane.io/composition-resource-name"
+
+// maxRenderIterations is how many times render calls the Function to satisfy
+// its requirements for extra resources, like Crossplane does.
+const maxRenderIterations = 5
+
+// RenderCmd renders the resources the Function composes for an XR, without
+// serving gRPC or calling AWS.
+type RenderCmd struct {
+	XR        string `arg:"" help:"YAML file containing the composite resource (XR)." type:"existingfile"`
+	Input     string `arg:"" help:"YAML file containing the Function's Input." type:"existingfile"`
+	Offerings string `arg:"" help:"YAML or JSON file containing the offerings catalog to use instead of AWS." type:"existingfile"`
+
+	Observed       string `help:"YAML file containing observed composed resources, annotated with their crossplane.io/composition-resource-name." type:"existingfile"`
+	ExtraResources string `help:"YAML file containing extra resources the Function may request, like policy ConfigMaps." type:"existingfile"`
+}
+
+// Run the render command.
+func (c *RenderCmd) Run(cli *CLI) error {
+	log, err := function.NewLogger(cli.Debug)
+	if err != nil {
+		return err
+	}
+	return c.render(context.Background(), log, os.Stdout, os.Stderr)
+}
+
+// render the Function's output, writing composed resources to out and
+// results to results. It returns an error if the Function returns a fatal
+// result.
+func (c *RenderCmd) render(ctx context.Context, log logging.Logger, out, results io.Writer) error {
+	req, err := c.request()
+	if err != nil {
+		return err
+	}
+	cat, err := loadCatalog(c.Offerings)
+	if err != nil {
+		return err
+	}
+	extra, err := readObjects(c.ExtraResources)
+	if err != nil {
+		return err
+	}
+
+	f := &Function{log: log, ec2: cat.client, azure: cat}
+	rsp, err := runFunction(ctx, f, req, extra)
+	if err != nil {
+		return err
+	}
+
+	fatal := false
+	for _, r := range rsp.GetResults() {
+		fmt.Fprintf(results, "%s: %s\n", r.GetSeverity(), r.GetMessage())
+		fatal = fatal || r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL
+	}
+	if fatal {
+		return errors.New("Function returned a fatal result")
+	}
+
+	return writeDesired(out, req.GetObserved().GetComposite(), rsp.GetDesired())
+}
+
+// request builds the RunFunctionRequest Crossplane would send the Function.
+func (c *RenderCmd) request() (*fnv1.RunFunctionRequest, error) {
+	xrs, err := readObjects(c.XR)
+	if err != nil {
+		return nil, err
+	}
+	if len(xrs) != 1 {
+		return nil, errors.Errorf("%q must contain exactly one composite resource, found %d", c.XR, len(xrs))
+	}
+	xr, err := structpb.NewStruct(xrs[0].Object)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot convert composite resource to %T", xr)
+	}
+
+	ins, err := readObjects(c.Input)
+	if err != nil {
+		return nil, err
+	}
+	if len(ins) != 1 {
+		return nil, errors.Errorf("%q must contain exactly one Input, found %d", c.Input, len(ins))
+	}
+	in, err := structpb.NewStruct(ins[0].Object)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot convert Input to %T", in)
+	}
+
+	observed, err := readObjects(c.Observed)
+	if err != nil {
+		return nil, err
+	}
+	resources := make(map[string]*fnv1.Resource, len(observed))
+	for _, o := range observed {
+		name := o.GetAnnotations()[annotationCompositionResourceName]
+		if name == "" {
+			return nil, errors.Errorf("observed %s %q has no %s annotation", o.GetKind(), o.GetName(), annotationCompositionResourceName)
+		}
+		s, err := structpb.NewStruct(o.Object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot convert observed %s %q to %T", o.GetKind(), o.GetName(), s)
+		}
+		resources[name] = &fnv1.Resource{Resource: s}
+	}
+
+	return &fnv1.RunFunctionRequest{
+		Meta:     &fnv1.RequestMeta{Tag: "render"},
+		Input:    in,
+		Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: resources},
+	}, nil
+}
+
+// runFunction runs the Function like Crossplane would, calling it again with
+// the extra resources it requires until its requirements are satisfied.
+func runFunction(ctx context.Context, f *Function, req *fnv1.RunFunctionRequest, extra []*unstructured.Unstructured) (*fnv1.RunFunctionResponse, error) {
+	for i := 0; ; i++ {
+		rsp, err := f.RunFunction(ctx, req)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot run Function")
+		}
+		if rsp.GetRequirements().GetExtraResources() == nil || i == maxRenderIterations-1 {
+			return rsp, nil
+		}
+		next := selectExtraResources(rsp.GetRequirements(), extra)
+		if sameExtraResources(req.GetExtraResources(), next) {
+			return rsp, nil
+		}
+		req.ExtraResources = next
+	}
+}
+
+// readObjects reads every object in a YAML or JSON file. It returns no objects
+// if path is empty.
+func readObjects(path string) ([]*unstructured.Unstructured, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path) //nolint:gosec // Reading a user supplied file is intended.
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open %q", path)
+	}
+	defer f.Close() //nolint:errcheck // Only closing a file we read.
+
+	var objs []*unstructured.Unstructured
+	d := k8syaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		err := d.Decode(&u.Object)
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse %q", path)
+		}
+		if len(u.Object) > 0 {
+			objs = append(objs, u)
+		}
+	}
+}
+
+// selectExtraResources returns the extra resources that satisfy the supplied
+// requirements.
+func selectExtraResources(rq *fnv1.Requirements, extra []*unstructured.Unstructured) map[string]*fnv1.Resources {
+	out := make(map[string]*fnv1.Resources, len(rq.GetExtraResources()))
+	for key, sel := range rq.GetExtraResources() {
+		items := []*fnv1.Resource{}
+		for _, u := range extra {
+			if !matches(sel, u) {
+				continue
+			}
+			s, err := structpb.NewStruct(u.Object)
+			if err != nil {
+				continue
+			}
+			items = append(items, &fnv1.Resource{Resource: s})
+		}
+		out[key] = &fnv1.Resources{Items: items}
+	}
+	return out
+}
+
+// matches returns true if the supplied resource matches the supplied selector.
+func matches(sel *fnv1.ResourceSelector, u *unstructured.Unstructured) bool {
+	if u.GetAPIVersion() != sel.GetApiVersion() || u.GetKind() != sel.GetKind() {
+		return false
+	}
+	if n := sel.GetMatchName(); n != "" {
+		return u.GetName() == n
+	}
+	for k, v := range sel.GetMatchLabels().GetLabels() {
+		if u.GetLabels()[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// sameExtraResources returns true if a and b hold the same extra resources.
+func sameExtraResources(a, b map[string]*fnv1.Resources) bool {
+	return proto.Equal(&fnv1.RunFunctionRequest{ExtraResources: a}, &fnv1.RunFunctionRequest{ExtraResources: b})
+}
+
+// mergeInto recursively merges src into dst. Values in src win, except that
+// nested objects are merged rather than replaced.
+func mergeInto(dst, src map[string]any) {
+	for k, v := range src {
+		sm, sok := v.(map[string]any)
+		dm, dok := dst[k].(map[string]any)
+		if sok && dok {
+			mergeInto(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// writeDesired writes the desired XR, merged over the observed XR, and every
+// desired composed resource to w as a stream of YAML documents.
+func writeDesired(w io.Writer, observed *fnv1.Resource, desired *fnv1.State) error {
+	xr := &unstructured.Unstructured{Object: observed.GetResource().AsMap()}
+	mergeInto(xr.Object, desired.GetComposite().GetResource().AsMap())
+	docs := []*unstructured.Unstructured{xr}
+
+	names := make([]string, 0, len(desired.GetResources()))
+	for name := range desired.GetResources() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		u := &unstructured.Unstructured{Object: desired.GetResources()[name].GetResource().AsMap()}
+		anns := u.GetAnnotations()
+		if anns == nil {
+			anns = map[string]string{}
+		}
+		anns[annotationCompositionResourceName] = name
+		u.SetAnnotations(anns)
+		docs = append(docs, u)
+	}
+
+	for _, u := range docs {
+		if err := writeYAML(w, u.Object); err != nil {
+			return errors.Wrapf(err, "cannot write %s %q", u.GetKind(), u.GetName())
+		}
+	}
+	return nil
+}
+
+// writeYAML writes obj to w as a YAML document.
+func writeYAML(w io.Writer, obj map[string]any) error {
+	j, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal to JSON")
+	}
+	y, err := yaml.JSONToYAML(j)
+	if err != nil {
+		return errors.Wrap(err, "cannot convert JSON to YAML")
+	}
+	_, err = fmt.Fprintf(w, "---\n%s", y)
+	return err
+}
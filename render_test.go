@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRender(t *testing.T) {
+	type want struct {
+		out     string
+		results string
+		err     bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		cmd    RenderCmd
+		want   want
+	}{
+		"Example": {
+			reason: "The example XR should render to a NodePool using the example offerings catalog",
+			cmd: RenderCmd{
+				XR:        "example/xr.yaml",
+				Input:     "example/input.yaml",
+				Offerings: "example/offerings.yaml",
+			},
+			want: want{
+				out: `---
+apiVersion: example.crossplane.io/v1alpha1
+kind: XNodePool
+metadata:
+  name: example-xr
+spec:
+  AwsRegion: us-east-1
+  CxEnv: production
+---
+apiVersion: karpenter.sh/v1
+kind: NodePool
+metadata:
+  annotations:
+    crossplane.io/composition-resource-name: nodepool
+  name: example-xr
+spec:
+  disruption:
+    consolidateAfter: Never
+    consolidationPolicy: WhenEmptyOrUnderutilized
+  limits:
+    cpu: "2"
+    memory: 2000Mi
+  template:
+    spec:
+      expireAfter: Never
+      nodeClassRef:
+        group: karpenter.sh
+        kind: EC2NodeClass
+        name: default2
+      requirements:
+      - key: karpenter.k8s.aws/instance-category
+        operator: In
+        values:
+        - m
+        - c
+status:
+  nodeClassObservedGeneration: 0
+`,
+				results: "SEVERITY_NORMAL: I was run with input \"Hello world\"!\n",
+			},
+		},
+		"MissingInput": {
+			reason: "Render should fail when the Input can't be read",
+			cmd: RenderCmd{
+				XR:        "example/xr.yaml",
+				Input:     "example/missing.yaml",
+				Offerings: "example/offerings.yaml",
+			},
+			want: want{
+				err: true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			out, results := &bytes.Buffer{}, &bytes.Buffer{}
+			err := tc.cmd.render(context.Background(), logging.NewNopLogger(), out, results)
+
+			if diff := cmp.Diff(tc.want, want{out: out.String(), results: results.String(), err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nrender(...): -want, +got:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+		})
+	}
+}
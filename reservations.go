@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// reservationsStatusField is the XR status field the Function writes the
+// capacity reservations an XR's nodes may use to.
+const reservationsStatusField = "status.capacityReservations"
+
+// The Karpenter EC2NodeClass API.
+const (
+	ec2NodeClassAPIVersion = "karpenter.k8s.aws/v1"
+	ec2NodeClassKind       = "EC2NodeClass"
+	defaultAMIAlias        = "al2023@latest"
+)
+
+// A reservation is an active On-Demand Capacity Reservation.
+type reservation struct {
+	ID                 string
+	InstanceType       string
+	AvailabilityZone   string
+	TotalInstances     int32
+	AvailableInstances int32
+}
+
+// usesReservations returns true if XRs of the supplied environment use the
+// supplied capacity reservations.
+func usesReservations(cr *v1beta1.CapacityReservations, environment string) bool {
+	return cr != nil && (len(cr.Environments) == 0 || slices.Contains(cr.Environments, environment))
+}
+
+// CapacityReservations returns the active capacity reservations in the
+// supplied region that match any of the supplied selector terms, ordered by
+// ID.
+func (p *awsProvider) CapacityReservations(ctx context.Context, region string, terms []v1beta1.CapacityReservationSelectorTerm) ([]reservation, error) {
+	ec2Client, err := p.client(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := describeCapacityReservations(ctx, ec2Client, &ec2.DescribeCapacityReservationsInput{
+		Filters: []types.Filter{{Name: aws.String("state"), Values: []string{string(types.CapacityReservationStateActive)}}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to describe capacity reservations")
+	}
+
+	var rs []reservation
+	for _, cr := range out.CapacityReservations {
+		if cr.State != types.CapacityReservationStateActive || !slices.ContainsFunc(terms, func(t v1beta1.CapacityReservationSelectorTerm) bool { return matchesReservation(t, cr) }) {
+			continue
+		}
+		rs = append(rs, reservation{
+			ID:                 aws.ToString(cr.CapacityReservationId),
+			InstanceType:       aws.ToString(cr.InstanceType),
+			AvailabilityZone:   aws.ToString(cr.AvailabilityZone),
+			TotalInstances:     aws.ToInt32(cr.TotalInstanceCount),
+			AvailableInstances: aws.ToInt32(cr.AvailableInstanceCount),
+		})
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].ID < rs[j].ID })
+	p.log.Debug("Found capacity reservations", "region", region, "count", len(rs))
+	return rs, nil
+}
+
+// reportReservations writes the active capacity reservations in the supplied
+// region that match any of the supplied selector terms to the XR's status. It
+// returns an error if none match.
+func (p *awsProvider) reportReservations(ctx context.Context, rsp *fnv1.RunFunctionResponse, region string, terms []v1beta1.CapacityReservationSelectorTerm) error {
+	rs, err := p.CapacityReservations(ctx, region, terms)
+	if err != nil {
+		return err
+	}
+	if len(rs) == 0 {
+		return errors.Errorf("no active capacity reservations in %s match the Input's capacityReservations.selectorTerms", region)
+	}
+	return setCompositeField(rsp, reservationsStatusField, reservationsStatus(rs))
+}
+
+// matchesReservation returns true if the supplied selector term selects the
+// supplied capacity reservation, like Karpenter does. A term selects by ID,
+// or else by every one of its tags and its owner. A term with neither an ID
+// nor tags selects nothing.
+func matchesReservation(t v1beta1.CapacityReservationSelectorTerm, cr types.CapacityReservation) bool {
+	if t.ID != "" {
+		return t.ID == aws.ToString(cr.CapacityReservationId)
+	}
+	if len(t.Tags) == 0 || (t.OwnerID != "" && t.OwnerID != aws.ToString(cr.OwnerId)) {
+		return false
+	}
+	tags := make(map[string]string, len(cr.Tags))
+	for _, tag := range cr.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	for k, v := range t.Tags {
+		if tv, ok := tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedCapacityRequirement lets a NodePool launch nodes in capacity
+// reservations, falling back to on-demand capacity once they're used up.
+func reservedCapacityRequirement() karpenterv1.NodeSelectorRequirementWithMinValues {
+	r := karpenterv1.NodeSelectorRequirementWithMinValues{}
+	r.Key = karpenterv1.CapacityTypeLabelKey
+	r.Operator = "In"
+	r.Values = []string{karpenterv1.CapacityTypeReserved, karpenterv1.CapacityTypeOnDemand}
+	return r
+}
+
+// newEC2NodeClass returns a Karpenter EC2NodeClass configured by the supplied
+// Input, that selects capacity reservations by the supplied terms.
+func newEC2NodeClass(name string, cfg v1beta1.EC2NodeClass, reservations []v1beta1.CapacityReservationSelectorTerm) (*composed.Unstructured, error) {
+	spec := map[string]any{
+		"role":             cfg.Role,
+		"amiSelectorTerms": []any{map[string]any{"alias": withDefault(cfg.AMIAlias, defaultAMIAlias)}},
+	}
+	if terms := selectorTerms(cfg.SubnetSelectorTerms); len(terms) > 0 {
+		spec["subnetSelectorTerms"] = terms
+	}
+	if terms := selectorTerms(cfg.SecurityGroupSelectorTerms); len(terms) > 0 {
+		spec["securityGroupSelectorTerms"] = terms
+	}
+	crTerms := make([]any, 0, len(reservations))
+	for _, t := range reservations {
+		term := map[string]any{}
+		if t.ID != "" {
+			term["id"] = t.ID
+		}
+		if t.OwnerID != "" {
+			term["ownerID"] = t.OwnerID
+		}
+		if len(t.Tags) > 0 {
+			term["tags"] = t.Tags
+		}
+		crTerms = append(crTerms, term)
+	}
+	spec["capacityReservationSelectorTerms"] = crTerms
+	if len(cfg.Tags) > 0 {
+		spec["tags"] = cfg.Tags
+	}
+
+	nc := composed.New()
+	nc.SetAPIVersion(ec2NodeClassAPIVersion)
+	nc.SetKind(ec2NodeClassKind)
+	nc.SetName(name)
+	if err := nc.SetValue("spec", spec); err != nil {
+		return nil, errors.Wrapf(err, "cannot set spec of %s", ec2NodeClassKind)
+	}
+	return nc, nil
+}
+
+// reservationsStatus returns the status the Function reports the supplied
+// capacity reservations as: each reservation, and how many instances they
+// reserve and have left in total.
+func reservationsStatus(rs []reservation) map[string]any {
+	var total, available int64
+	items := make([]any, len(rs))
+	for i, r := range rs {
+		total += int64(r.TotalInstances)
+		available += int64(r.AvailableInstances)
+		items[i] = map[string]any{
+			"id":                 r.ID,
+			"instanceType":       r.InstanceType,
+			"availabilityZone":   r.AvailabilityZone,
+			"totalInstances":     int64(r.TotalInstances),
+			"availableInstances": int64(r.AvailableInstances),
+		}
+	}
+	return map[string]any{
+		"totalInstances":     total,
+		"availableInstances": available,
+		"reservations":       items,
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestMatchesReservation(t *testing.T) {
+	cr := types.CapacityReservation{
+		CapacityReservationId: aws.String("cr-0123456789abcdef0"),
+		OwnerId:               aws.String("123456789012"),
+		Tags: []types.Tag{
+			{Key: aws.String("purpose"), Value: aws.String("production")},
+			{Key: aws.String("team"), Value: aws.String("platform")},
+		},
+	}
+
+	cases := map[string]struct {
+		reason string
+		term   v1beta1.CapacityReservationSelectorTerm
+		want   bool
+	}{
+		"ID": {
+			reason: "A term should select the reservation with its ID.",
+			term:   v1beta1.CapacityReservationSelectorTerm{ID: "cr-0123456789abcdef0"},
+			want:   true,
+		},
+		"OtherID": {
+			reason: "A term shouldn't select reservations by tag if it has an ID.",
+			term:   v1beta1.CapacityReservationSelectorTerm{ID: "cr-0fedcba9876543210", Tags: map[string]string{"purpose": "production"}},
+			want:   false,
+		},
+		"Tags": {
+			reason: "A term should select reservations with all of its tags.",
+			term:   v1beta1.CapacityReservationSelectorTerm{Tags: map[string]string{"purpose": "production", "team": "platform"}},
+			want:   true,
+		},
+		"MissingTag": {
+			reason: "A term shouldn't select reservations missing one of its tags.",
+			term:   v1beta1.CapacityReservationSelectorTerm{Tags: map[string]string{"purpose": "production", "cost-center": "cc-4012"}},
+			want:   false,
+		},
+		"Owner": {
+			reason: "A term shouldn't select reservations another account owns.",
+			term:   v1beta1.CapacityReservationSelectorTerm{OwnerID: "210987654321", Tags: map[string]string{"purpose": "production"}},
+			want:   false,
+		},
+		"Empty": {
+			reason: "A term with neither an ID nor tags shouldn't select anything.",
+			term:   v1beta1.CapacityReservationSelectorTerm{OwnerID: "123456789012"},
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, matchesReservation(tc.term, cr)); diff != "" {
+				t.Errorf("\n%s\nmatchesReservation(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUsesReservations(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		cr     *v1beta1.CapacityReservations
+		want   bool
+	}{
+		"NotConfigured": {
+			reason: "XRs shouldn't use reservations the Input doesn't configure.",
+			want:   false,
+		},
+		"AllEnvironments": {
+			reason: "XRs of every environment should use reservations if the Input doesn't list environments.",
+			cr:     &v1beta1.CapacityReservations{},
+			want:   true,
+		},
+		"OtherEnvironment": {
+			reason: "XRs of environments the Input doesn't list shouldn't use reservations.",
+			cr:     &v1beta1.CapacityReservations{Environments: []string{"staging"}},
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, usesReservations(tc.cr, "production")); diff != "" {
+				t.Errorf("\n%s\nusesReservations(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
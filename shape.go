@@ -0,0 +1,197 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/response"
+	corev1 "k8s.io/api/core/v1"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// shapeLabels are the well-known node labels of an instance's shape.
+type shapeLabels struct {
+	CPU        string
+	Memory     string
+	Generation string
+	Size       string
+}
+
+// A shaper constrains the shape of the instances a NodePool launches.
+type shaper interface {
+	// ShapeLabels are the well-known node labels of an instance's shape.
+	ShapeLabels() shapeLabels
+}
+
+// ShapeLabels are the karpenter.k8s.aws instance shape labels.
+func (p *awsProvider) ShapeLabels() shapeLabels {
+	return shapeLabels{
+		CPU:        "karpenter.k8s.aws/instance-cpu",
+		Memory:     "karpenter.k8s.aws/instance-memory",
+		Generation: "karpenter.k8s.aws/instance-generation",
+		Size:       "karpenter.k8s.aws/instance-size",
+	}
+}
+
+// ShapeLabels are the eks.amazonaws.com instance shape labels.
+func (p *autoModeProvider) ShapeLabels() shapeLabels {
+	return shapeLabels{
+		CPU:        "eks.amazonaws.com/instance-cpu",
+		Memory:     "eks.amazonaws.com/instance-memory",
+		Generation: "eks.amazonaws.com/instance-generation",
+		Size:       "eks.amazonaws.com/instance-size",
+	}
+}
+
+// instanceShape returns the requirements that constrain the instances the
+// supplied provider's NodePools launch to the supplied shape. It returns none,
+// and warns, if the supplied autoscaler doesn't compose NodePools the shape
+// could constrain.
+func instanceShape(rsp *fnv1.RunFunctionResponse, s *v1beta1.InstanceShape, prov nodeProvider, autoscaler string) ([]karpenterv1.NodeSelectorRequirementWithMinValues, error) {
+	if !composesNodePool(autoscaler) {
+		response.Warning(rsp, errors.Errorf("the Input's shape doesn't constrain the instance types of autoscaler %q", autoscaler)).TargetCompositeAndClaim()
+		return nil, nil
+	}
+	sp, ok := prov.(shaper)
+	if !ok {
+		return nil, errors.Errorf("the Input's shape requires provider %q", providerAWS)
+	}
+	reqs, err := shapeRequirements(s, sp.ShapeLabels())
+	return reqs, errors.Wrap(err, "invalid shape in the Input")
+}
+
+// shapeRequirements returns the NodePool requirements that constrain
+// instances to the supplied shape. Karpenter's Gt and Lt operators are
+// exclusive, so inclusive bounds are widened by one. Instance memory is in
+// MiB.
+func shapeRequirements(s *v1beta1.InstanceShape, l shapeLabels) ([]karpenterv1.NodeSelectorRequirementWithMinValues, error) {
+	if s.MinVCPUs != nil && s.MaxVCPUs != nil && *s.MinVCPUs > *s.MaxVCPUs {
+		return nil, errors.Errorf("minVCPUs %d is more than maxVCPUs %d", *s.MinVCPUs, *s.MaxVCPUs)
+	}
+	if s.MinMemoryPerVCPU != nil && s.MinVCPUs == nil {
+		return nil, errors.New("minMemoryPerVCPU requires minVCPUs")
+	}
+	if s.MaxMemoryPerVCPU != nil && s.MaxVCPUs == nil {
+		return nil, errors.New("maxMemoryPerVCPU requires maxVCPUs")
+	}
+	if s.MinMemoryPerVCPU != nil && s.MaxMemoryPerVCPU != nil && s.MinMemoryPerVCPU.Cmp(*s.MaxMemoryPerVCPU) > 0 {
+		return nil, errors.Errorf("minMemoryPerVCPU %s is more than maxMemoryPerVCPU %s", s.MinMemoryPerVCPU, s.MaxMemoryPerVCPU)
+	}
+
+	var reqs []karpenterv1.NodeSelectorRequirementWithMinValues
+	req := func(key string, op corev1.NodeSelectorOperator, values ...string) {
+		r := karpenterv1.NodeSelectorRequirementWithMinValues{}
+		r.Key = key
+		r.Operator = op
+		r.Values = values
+		reqs = append(reqs, r)
+	}
+	if s.MinVCPUs != nil {
+		req(l.CPU, corev1.NodeSelectorOpGt, strconv.FormatInt(*s.MinVCPUs-1, 10))
+	}
+	if s.MaxVCPUs != nil {
+		req(l.CPU, corev1.NodeSelectorOpLt, strconv.FormatInt(*s.MaxVCPUs+1, 10))
+	}
+	if s.MinMemoryPerVCPU != nil {
+		req(l.Memory, corev1.NodeSelectorOpGt, strconv.FormatInt(*s.MinVCPUs*ceilMiB(*s.MinMemoryPerVCPU)-1, 10))
+	}
+	if s.MaxMemoryPerVCPU != nil {
+		req(l.Memory, corev1.NodeSelectorOpLt, strconv.FormatInt(*s.MaxVCPUs*(s.MaxMemoryPerVCPU.Value()>>20)+1, 10))
+	}
+	if s.MinGeneration != nil {
+		req(l.Generation, corev1.NodeSelectorOpGt, strconv.FormatInt(*s.MinGeneration-1, 10))
+	}
+	if len(s.ExcludedSizes) > 0 {
+		req(l.Size, corev1.NodeSelectorOpNotIn, s.ExcludedSizes...)
+	}
+	return reqs, nil
+}
+
+// instanceGeneration returns the generation of the supplied instance type,
+// e.g. 8 for c8g.large, or "" if it has none.
+func instanceGeneration(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	digits := strings.TrimLeftFunc(family, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i := strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) }); i >= 0 {
+		digits = digits[:i]
+	}
+	return digits
+}
+
+// instanceSize returns the size of the supplied instance type, e.g. large for
+// c8g.large.
+func instanceSize(instanceType string) string {
+	_, size, _ := strings.Cut(instanceType, ".")
+	return size
+}
+
+// shapeLabelValues returns the values of the supplied shape labels Karpenter
+// gives nodes of the supplied instance type.
+func shapeLabelValues(it instanceType, l shapeLabels) map[string]string {
+	v := map[string]string{
+		l.CPU:    strconv.FormatInt(it.VCPUs, 10),
+		l.Memory: strconv.FormatInt(it.MemoryMiB, 10),
+		l.Size:   instanceSize(it.Name),
+	}
+	if g := instanceGeneration(it.Name); g != "" {
+		v[l.Generation] = g
+	}
+	return v
+}
+
+// satisfies returns true if a node with the supplied labels satisfies the
+// supplied requirement, like Karpenter decides. Nodes without the label
+// satisfy only NotIn requirements.
+func satisfies(r karpenterv1.NodeSelectorRequirementWithMinValues, labels map[string]string) bool {
+	v, ok := labels[r.Key]
+	switch r.Operator {
+	case corev1.NodeSelectorOpIn:
+		return ok && slices.Contains(r.Values, v)
+	case corev1.NodeSelectorOpNotIn:
+		return !ok || !slices.Contains(r.Values, v)
+	case corev1.NodeSelectorOpExists:
+		return ok
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !ok
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if !ok || len(r.Values) != 1 {
+			return false
+		}
+		have, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return false
+		}
+		bound, err := strconv.ParseInt(r.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if r.Operator == corev1.NodeSelectorOpGt {
+			return have > bound
+		}
+		return have < bound
+	}
+	return false
+}
+
+// permittedInstanceTypes returns the supplied instance types of the supplied
+// categories whose shape satisfies every one of the supplied requirements.
+func permittedInstanceTypes(its []instanceType, categories []string, reqs []karpenterv1.NodeSelectorRequirementWithMinValues, l shapeLabels) []instanceType {
+	var out []instanceType
+	for _, it := range its {
+		if !slices.Contains(categories, instanceCategory(it.Name)) {
+			continue
+		}
+		labels := shapeLabelValues(it, l)
+		if slices.ContainsFunc(reqs, func(r karpenterv1.NodeSelectorRequirementWithMinValues) bool { return !satisfies(r, labels) }) {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func requirement(key string, op corev1.NodeSelectorOperator, values ...string) karpenterv1.NodeSelectorRequirementWithMinValues {
+	r := karpenterv1.NodeSelectorRequirementWithMinValues{}
+	r.Key = key
+	r.Operator = op
+	r.Values = values
+	return r
+}
+
+func TestShapeRequirements(t *testing.T) {
+	l := (&awsProvider{}).ShapeLabels()
+	quantity := func(s string) *k8sresource.Quantity { q := k8sresource.MustParse(s); return &q }
+
+	type want struct {
+		reqs []karpenterv1.NodeSelectorRequirementWithMinValues
+		err  string
+	}
+	cases := map[string]struct {
+		reason string
+		shape  *v1beta1.InstanceShape
+		want   want
+	}{
+		"Empty": {
+			reason: "An empty shape shouldn't constrain instances.",
+			shape:  &v1beta1.InstanceShape{},
+		},
+		"Full": {
+			reason: "Inclusive bounds should become exclusive Gt and Lt requirements, and memory per vCPU should bound instance memory.",
+			shape: &v1beta1.InstanceShape{
+				MinVCPUs:         ptr.To[int64](2),
+				MaxVCPUs:         ptr.To[int64](16),
+				MinMemoryPerVCPU: quantity("4Gi"),
+				MaxMemoryPerVCPU: quantity("8Gi"),
+				MinGeneration:    ptr.To[int64](6),
+				ExcludedSizes:    []string{"metal"},
+			},
+			want: want{reqs: []karpenterv1.NodeSelectorRequirementWithMinValues{
+				requirement(l.CPU, corev1.NodeSelectorOpGt, "1"),
+				requirement(l.CPU, corev1.NodeSelectorOpLt, "17"),
+				requirement(l.Memory, corev1.NodeSelectorOpGt, "8191"),
+				requirement(l.Memory, corev1.NodeSelectorOpLt, "131073"),
+				requirement(l.Generation, corev1.NodeSelectorOpGt, "5"),
+				requirement(l.Size, corev1.NodeSelectorOpNotIn, "metal"),
+			}},
+		},
+		"VCPUsInverted": {
+			reason: "minVCPUs shouldn't be more than maxVCPUs.",
+			shape:  &v1beta1.InstanceShape{MinVCPUs: ptr.To[int64](8), MaxVCPUs: ptr.To[int64](4)},
+			want:   want{err: "minVCPUs 8 is more than maxVCPUs 4"},
+		},
+		"RatioWithoutVCPUs": {
+			reason: "Memory per vCPU can't bound instance memory without a vCPU bound.",
+			shape:  &v1beta1.InstanceShape{MinMemoryPerVCPU: quantity("4Gi")},
+			want:   want{err: "minMemoryPerVCPU requires minVCPUs"},
+		},
+		"RatioInverted": {
+			reason: "minMemoryPerVCPU shouldn't be more than maxMemoryPerVCPU.",
+			shape: &v1beta1.InstanceShape{
+				MinVCPUs:         ptr.To[int64](2),
+				MaxVCPUs:         ptr.To[int64](4),
+				MinMemoryPerVCPU: quantity("8Gi"),
+				MaxMemoryPerVCPU: quantity("2Gi"),
+			},
+			want: want{err: "minMemoryPerVCPU 8Gi is more than maxMemoryPerVCPU 2Gi"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reqs, err := shapeRequirements(tc.shape, l)
+			got := want{reqs: reqs}
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nshapeRequirements(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInstanceGeneration(t *testing.T) {
+	cases := map[string]string{
+		"m5.large":     "5",
+		"c8g.16xlarge": "8",
+		"m7i-flex.2xl": "7",
+		"t2.micro":     "2",
+		"mac.metal":    "",
+	}
+	for instanceType, want := range cases {
+		t.Run(instanceType, func(t *testing.T) {
+			if diff := cmp.Diff(want, instanceGeneration(instanceType)); diff != "" {
+				t.Errorf("instanceGeneration(%q): -want, +got:\n%s", instanceType, diff)
+			}
+		})
+	}
+}
+
+func TestPermittedInstanceTypes(t *testing.T) {
+	l := (&awsProvider{}).ShapeLabels()
+	its := []instanceType{
+		{Name: "c5.large", VCPUs: 2, MemoryMiB: 4096},
+		{Name: "m5.large", VCPUs: 2, MemoryMiB: 8192},
+		{Name: "m6i.metal", VCPUs: 128, MemoryMiB: 524288},
+		{Name: "m6i.xlarge", VCPUs: 4, MemoryMiB: 16384},
+		{Name: "r6i.xlarge", VCPUs: 4, MemoryMiB: 32768},
+	}
+
+	cases := map[string]struct {
+		reason     string
+		categories []string
+		reqs       []karpenterv1.NodeSelectorRequirementWithMinValues
+		want       []string
+	}{
+		"Categories": {
+			reason:     "Instance types of other categories shouldn't be permitted.",
+			categories: []string{"m"},
+			want:       []string{"m5.large", "m6i.metal", "m6i.xlarge"},
+		},
+		"Shape": {
+			reason:     "Instance types should satisfy every requirement.",
+			categories: []string{"c", "m", "r"},
+			reqs: []karpenterv1.NodeSelectorRequirementWithMinValues{
+				requirement(l.CPU, corev1.NodeSelectorOpGt, "1"),
+				requirement(l.Memory, corev1.NodeSelectorOpLt, "32769"),
+				requirement(l.Generation, corev1.NodeSelectorOpGt, "5"),
+				requirement(l.Size, corev1.NodeSelectorOpNotIn, "metal"),
+			},
+			want: []string{"m6i.xlarge", "r6i.xlarge"},
+		},
+		"None": {
+			reason:     "No instance type should be permitted if none satisfies the requirements.",
+			categories: []string{"c"},
+			reqs:       []karpenterv1.NodeSelectorRequirementWithMinValues{requirement(l.CPU, corev1.NodeSelectorOpGt, "2")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, it := range permittedInstanceTypes(its, tc.categories, tc.reqs, l) {
+				got = append(got, it.Name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\npermittedInstanceTypes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/function-sdk-go"
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// inputCRD is the generated CRD describing the schema of the Function's Input.
+//
+//go:embed package/input/template.fn.crossplane.io_inputs.yaml
+var inputCRD []byte
+
+// inputAPIVersion is the apiVersion of the Function's Input.
+const inputAPIVersion = "template.fn.crossplane.io/v1beta1"
+
+// Output formats of the validate command.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// ValidateCmd lints the Function's Input against the Function's rules.
+type ValidateCmd struct {
+	Inputs []string `arg:"" help:"YAML files containing Compositions or Inputs. Every pipeline step of a Composition with a template.fn.crossplane.io/v1beta1 Input is validated." type:"existingfile"`
+
+	XRs            []string `help:"YAML files containing sample composite resources (XRs) to validate each Input against." name:"xr" required:"" type:"existingfile"`
+	Offerings      string   `help:"YAML or JSON file containing the offerings catalog used to check the NodePool can launch." required:"" type:"existingfile"`
+	ExtraResources string   `help:"YAML file containing extra resources the Function may request, like policy ConfigMaps." type:"existingfile"`
+	Output         string   `help:"Format of the report. One of text or json." default:"text" enum:"text,json" short:"o"`
+}
+
+// A validationReport is the result of validating Inputs.
+type validationReport struct {
+	Valid  bool          `json:"valid"`
+	Inputs []inputReport `json:"inputs"`
+}
+
+// An inputReport is the result of validating a single Input.
+type inputReport struct {
+	Source string     `json:"source"`
+	Errors []string   `json:"errors,omitempty"`
+	XRs    []xrReport `json:"xrs,omitempty"`
+}
+
+// An xrReport is the result of running the Function with an Input for a
+// sample XR.
+type xrReport struct {
+	Name        string            `json:"name"`
+	Environment string            `json:"environment,omitempty"`
+	Region      string            `json:"region,omitempty"`
+	Limits      map[string]string `json:"limits,omitempty"`
+	Categories  []string          `json:"instanceCategories,omitempty"`
+	Errors      []string          `json:"errors,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"`
+}
+
+// namedInput is an Input and where it was found.
+type namedInput struct {
+	source string
+	input  *unstructured.Unstructured
+}
+
+// Run the validate command.
+func (c *ValidateCmd) Run(cli *CLI) error {
+	log, err := function.NewLogger(cli.Debug)
+	if err != nil {
+		return err
+	}
+	report, err := c.validate(context.Background(), log)
+	if err != nil {
+		return err
+	}
+	if err := writeReport(os.Stdout, report, c.Output); err != nil {
+		return err
+	}
+	if !report.Valid {
+		return errors.New("validation failed")
+	}
+	return nil
+}
+
+// validate every Input against every sample XR.
+func (c *ValidateCmd) validate(ctx context.Context, log logging.Logger) (*validationReport, error) {
+	schema, err := inputSchema()
+	if err != nil {
+		return nil, err
+	}
+	cat, err := loadCatalog(c.Offerings)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := readObjects(c.ExtraResources)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []namedInput
+	for _, path := range c.Inputs {
+		found, err := findInputs(path)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, found...)
+	}
+	var xrs []*unstructured.Unstructured
+	for _, path := range c.XRs {
+		objs, err := readObjects(path)
+		if err != nil {
+			return nil, err
+		}
+		xrs = append(xrs, objs...)
+	}
+
+	f := &Function{log: log, ec2: cat.client, azure: cat}
+	report := &validationReport{Valid: true, Inputs: make([]inputReport, 0, len(inputs))}
+	for _, in := range inputs {
+		ir := inputReport{Source: in.source, Errors: validateInput(schema, in.input)}
+		if len(ir.Errors) == 0 {
+			for _, xr := range xrs {
+				xrr, err := validateXR(ctx, f, cat, in.input, xr, extra)
+				if err != nil {
+					return nil, err
+				}
+				ir.XRs = append(ir.XRs, xrr)
+				report.Valid = report.Valid && len(xrr.Errors) == 0
+			}
+		}
+		report.Valid = report.Valid && len(ir.Errors) == 0
+		report.Inputs = append(report.Inputs, ir)
+	}
+	return report, nil
+}
+
+// inputSchema returns the OpenAPI schema of the Function's Input.
+func inputSchema() (*spec.Schema, error) {
+	crd := &struct {
+		Spec struct {
+			Versions []struct {
+				Name   string `json:"name"`
+				Schema struct {
+					OpenAPIV3Schema json.RawMessage `json:"openAPIV3Schema"`
+				} `json:"schema"`
+			} `json:"versions"`
+		} `json:"spec"`
+	}{}
+	if err := yaml.Unmarshal(inputCRD, crd); err != nil {
+		return nil, errors.Wrap(err, "cannot parse Input CRD")
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Name != "v1beta1" {
+			continue
+		}
+		s := &spec.Schema{}
+		if err := json.Unmarshal(v.Schema.OpenAPIV3Schema, s); err != nil {
+			return nil, errors.Wrap(err, "cannot parse Input schema")
+		}
+		return s, nil
+	}
+	return nil, errors.New("Input CRD has no v1beta1 schema")
+}
+
+// findInputs returns the Function Inputs in the supplied file. The file may
+// contain Inputs, or Compositions whose pipeline steps have Inputs.
+func findInputs(path string) ([]namedInput, error) {
+	objs, err := readObjects(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []namedInput
+	for i, o := range objs {
+		if isInput(o.Object) {
+			found = append(found, namedInput{source: fmt.Sprintf("%s: Input (document %d)", path, i+1), input: o})
+			continue
+		}
+		if o.GetKind() != "Composition" {
+			continue
+		}
+		steps, _, err := unstructured.NestedSlice(o.Object, "spec", "pipeline")
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read pipeline of Composition %q", o.GetName())
+		}
+		for _, s := range steps {
+			step, ok := s.(map[string]any)
+			if !ok {
+				continue
+			}
+			in, ok := step["input"].(map[string]any)
+			if !ok || !isInput(in) {
+				continue
+			}
+			found = append(found, namedInput{
+				source: fmt.Sprintf("%s: Composition %s, step %v", path, o.GetName(), step["step"]),
+				input:  &unstructured.Unstructured{Object: in},
+			})
+		}
+	}
+	return found, nil
+}
+
+func isInput(obj map[string]any) bool {
+	return obj["apiVersion"] == inputAPIVersion && obj["kind"] == "Input"
+}
+
+// validateInput validates an Input against the Input schema. Fields the schema
+// doesn't know are errors, because they would be silently ignored.
+func validateInput(schema *spec.Schema, in *unstructured.Unstructured) []string {
+	var errs []string
+	for _, err := range validate.NewSchemaValidator(schema, nil, "", strfmt.Default).Validate(in.Object).Errors {
+		errs = append(errs, err.Error())
+	}
+
+	j, err := json.Marshal(in.Object)
+	if err != nil {
+		return append(errs, err.Error())
+	}
+	if err := yaml.UnmarshalStrict(j, &v1beta1.Input{}); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+// validateXR runs the Function with the supplied Input for the supplied XR, and
+// checks the NodePool it composes can launch instances.
+func validateXR(ctx context.Context, f *Function, cat *catalog, in, xr *unstructured.Unstructured, extra []*unstructured.Unstructured) (xrReport, error) {
+	r := xrReport{Name: xr.GetName()}
+	r.Environment, _, _ = unstructured.NestedString(xr.Object, "spec", "CxEnv")
+	provider, _, _ := unstructured.NestedString(xr.Object, "spec", "Provider")
+	if provider == providerAzure {
+		r.Region, _, _ = unstructured.NestedString(xr.Object, "spec", "AzureLocation")
+	} else {
+		r.Region, _, _ = unstructured.NestedString(xr.Object, "spec", "AwsRegion")
+	}
+
+	is, err := structpb.NewStruct(in.Object)
+	if err != nil {
+		return r, errors.Wrapf(err, "cannot convert Input to %T", is)
+	}
+	xs, err := structpb.NewStruct(xr.Object)
+	if err != nil {
+		return r, errors.Wrapf(err, "cannot convert XR %q to %T", xr.GetName(), xs)
+	}
+	req := &fnv1.RunFunctionRequest{
+		Meta:     &fnv1.RequestMeta{Tag: "validate"},
+		Input:    is,
+		Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xs}},
+	}
+	rsp, err := runFunction(ctx, f, req, extra)
+	if err != nil {
+		return r, err
+	}
+
+	for _, res := range rsp.GetResults() {
+		switch res.GetSeverity() { //nolint:exhaustive // Normal results aren't reported.
+		case fnv1.Severity_SEVERITY_FATAL:
+			r.Errors = append(r.Errors, res.GetMessage())
+		case fnv1.Severity_SEVERITY_WARNING:
+			r.Warnings = append(r.Warnings, res.GetMessage())
+		}
+	}
+
+	if ng, ok := rsp.GetDesired().GetResources()["nodegroup"]; ok {
+		return validateNodeGroup(r, cat, ng.GetResource().AsMap()), nil
+	}
+	if md, ok := rsp.GetDesired().GetResources()["machinedeployment"]; ok {
+		tmpl := rsp.GetDesired().GetResources()[string(resourceNameAWSMachineTemplate)]
+		return validateMachineDeployment(r, cat, md.GetResource().AsMap(), tmpl.GetResource().AsMap()), nil
+	}
+	np, ok := rsp.GetDesired().GetResources()["nodepool"]
+	if !ok {
+		return r, nil
+	}
+	// A delivered NodePool is the manifest of a provider-kubernetes Object.
+	nodePool := objectManifest(np.GetResource().AsMap())
+	limits, _, _ := unstructured.NestedStringMap(nodePool, "spec", "limits")
+	r.Limits = limits
+
+	prov, err := f.provider(provider)
+	if err != nil {
+		return r, nil //nolint:nilerr // The Function already reported the invalid provider.
+	}
+	if autoscaler, _, _ := unstructured.NestedString(xr.Object, "spec", "Autoscaler"); autoscaler == autoscalerEKSAutoMode {
+		if prov, err = eksAutoMode(prov); err != nil {
+			return r, nil //nolint:nilerr // The Function already reported the invalid autoscaler.
+		}
+	}
+	offered, ok := cat.offeredCategories(provider, r.Region)
+	if !ok {
+		r.Errors = append(r.Errors, fmt.Sprintf("region %q is not in the offerings catalog", r.Region))
+		return r, nil
+	}
+	reqs, _, _ := unstructured.NestedSlice(nodePool, "spec", "template", "spec", "requirements")
+	for _, rq := range reqs {
+		m, ok := rq.(map[string]any)
+		if !ok || m["key"] != prov.CategoryLabel() {
+			continue
+		}
+		values, _, _ := unstructured.NestedStringSlice(m, "values")
+		r.Categories = append(r.Categories, values...)
+		for _, v := range values {
+			if !offered[v] {
+				r.Errors = append(r.Errors, fmt.Sprintf("no instance types of category %q are offered in %s", v, r.Region))
+			}
+		}
+	}
+	return r, nil
+}
+
+// validateNodeGroup checks every instance type of the supplied NodeGroup is
+// offered in the XR's region.
+func validateNodeGroup(r xrReport, cat *catalog, nodeGroup map[string]any) xrReport {
+	if sc, _, _ := unstructured.NestedSlice(nodeGroup, "spec", "forProvider", "scalingConfig"); len(sc) == 1 {
+		if m, ok := sc[0].(map[string]any); ok {
+			r.Limits = map[string]string{
+				"minSize": fmt.Sprint(m["minSize"]),
+				"maxSize": fmt.Sprint(m["maxSize"]),
+			}
+		}
+	}
+
+	types, _, _ := unstructured.NestedStringSlice(nodeGroup, "spec", "forProvider", "instanceTypes")
+	return validateInstanceTypes(r, cat, types)
+}
+
+// validateMachineDeployment checks the instance type of the supplied
+// MachineDeployment's AWSMachineTemplate is offered in the XR's region.
+func validateMachineDeployment(r xrReport, cat *catalog, machineDeployment, machineTemplate map[string]any) xrReport {
+	if replicas, found, _ := unstructured.NestedFieldNoCopy(machineDeployment, "spec", "replicas"); found {
+		r.Limits = map[string]string{"replicas": fmt.Sprint(replicas)}
+	}
+	t, _, _ := unstructured.NestedString(machineTemplate, "spec", "template", "spec", "instanceType")
+	return validateInstanceTypes(r, cat, []string{t})
+}
+
+// validateInstanceTypes checks every supplied instance type is offered in the
+// XR's region.
+func validateInstanceTypes(r xrReport, cat *catalog, types []string) xrReport {
+	region, ok := cat.Regions[r.Region]
+	if !ok {
+		r.Errors = append(r.Errors, fmt.Sprintf("region %q is not in the offerings catalog", r.Region))
+		return r
+	}
+	offered := map[string]bool{}
+	for _, t := range region.InstanceTypes {
+		offered[t] = true
+	}
+	for _, t := range types {
+		if c := instanceCategory(t); !slices.Contains(r.Categories, c) {
+			r.Categories = append(r.Categories, c)
+		}
+		if !offered[t] {
+			r.Errors = append(r.Errors, fmt.Sprintf("instance type %q is not offered in %s", t, r.Region))
+		}
+	}
+	return r
+}
+
+// writeReport writes the report to w in the supplied format.
+func writeReport(w io.Writer, r *validationReport, format string) error {
+	if format == outputJSON {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(r)
+	}
+
+	b := &strings.Builder{}
+	for _, in := range r.Inputs {
+		fmt.Fprintf(b, "%s\n", in.Source)
+		for _, e := range in.Errors {
+			fmt.Fprintf(b, "  ERROR: %s\n", e)
+		}
+		for _, xr := range in.XRs {
+			status := "OK"
+			if len(xr.Errors) > 0 {
+				status = "FAILED"
+			}
+			fmt.Fprintf(b, "  XR %s (environment %q, region %q): %s\n", xr.Name, xr.Environment, xr.Region, status)
+			if len(xr.Limits) > 0 {
+				keys := make([]string, 0, len(xr.Limits))
+				for k := range xr.Limits {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				limits := make([]string, len(keys))
+				for i, k := range keys {
+					limits[i] = k + "=" + xr.Limits[k]
+				}
+				fmt.Fprintf(b, "    limits: %s\n", strings.Join(limits, ", "))
+			}
+			if len(xr.Categories) > 0 {
+				fmt.Fprintf(b, "    instance categories: %s\n", strings.Join(xr.Categories, ", "))
+			}
+			for _, e := range xr.Errors {
+				fmt.Fprintf(b, "    ERROR: %s\n", e)
+			}
+			for _, wn := range xr.Warnings {
+				fmt.Fprintf(b, "    WARNING: %s\n", wn)
+			}
+		}
+	}
+	if r.Valid {
+		b.WriteString("Valid.\n")
+	} else {
+		b.WriteString("Invalid.\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
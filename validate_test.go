@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	inputs := write("inputs.yaml", `
+apiVersion: template.fn.crossplane.io/v1beta1
+kind: Input
+example: hi
+mode: Sideways
+typo: true
+---
+apiVersion: template.fn.crossplane.io/v1beta1
+kind: Input
+example: hi
+policy:
+  rules:
+  - name: no-c
+    expression: "nodePool.spec.template.spec.requirements.all(r, !('c' in r.values))"
+`)
+	xrs := write("xrs.yaml", `
+apiVersion: example.crossplane.io/v1alpha1
+kind: XNodePool
+metadata:
+  name: a
+spec:
+  CxEnv: production
+  AwsRegion: us-east-1
+---
+apiVersion: example.crossplane.io/v1alpha1
+kind: XNodePool
+metadata:
+  name: b
+spec:
+  CxEnv: development
+  AwsRegion: eu-west-1
+`)
+
+	delivered := write("delivered.yaml", `
+apiVersion: template.fn.crossplane.io/v1beta1
+kind: Input
+example: hi
+delivery:
+  providerConfigName: workload-cluster
+`)
+
+	cases := map[string]struct {
+		reason string
+		cmd    ValidateCmd
+		want   string
+	}{
+		"Valid": {
+			reason: "The example Composition should be valid for the example XR",
+			cmd: ValidateCmd{
+				Inputs:    []string{"example/composition.yaml"},
+				XRs:       []string{"example/xr.yaml"},
+				Offerings: "example/offerings.yaml",
+				Output:    outputText,
+			},
+			want: `example/composition.yaml: Composition function-nodepools, step run-the-template
+  XR example-xr (environment "production", region "us-east-1"): OK
+    limits: cpu=2, memory=2000Mi
+    instance categories: m, c
+Valid.
+`,
+		},
+		"Delivered": {
+			reason: "A NodePool wrapped in a provider-kubernetes Object should be validated like one that isn't",
+			cmd: ValidateCmd{
+				Inputs:    []string{delivered},
+				XRs:       []string{"example/xr.yaml"},
+				Offerings: "example/offerings.yaml",
+				Output:    outputText,
+			},
+			want: delivered + `: Input (document 1)
+  XR example-xr (environment "production", region "us-east-1"): OK
+    limits: cpu=2, memory=2000Mi
+    instance categories: m, c
+Valid.
+`,
+		},
+		"Invalid": {
+			reason: "Schema errors, policy violations and regions without offerings should be reported",
+			cmd: ValidateCmd{
+				Inputs:    []string{inputs},
+				XRs:       []string{xrs},
+				Offerings: "example/offerings.yaml",
+				Output:    outputText,
+			},
+			want: inputs + `: Input (document 1)
+  ERROR: mode in body should be one of [Compose Audit]
+  ERROR: error unmarshaling JSON: while decoding JSON: json: unknown field "typo"
+` + inputs + `: Input (document 2)
+  XR a (environment "production", region "us-east-1"): FAILED
+    ERROR: NodePool "a" violates 1 policy rule(s): no-c: nodePool.spec.template.spec.requirements.all(r, !('c' in r.values)) evaluated to false
+  XR b (environment "development", region "eu-west-1"): FAILED
+    limits: cpu=1, memory=1000Mi
+    ERROR: region "eu-west-1" is not in the offerings catalog
+Invalid.
+`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := tc.cmd.validate(context.Background(), logging.NewNopLogger())
+			if err != nil {
+				t.Fatalf("validate(...): %v", err)
+			}
+			got := &bytes.Buffer{}
+			if err := writeReport(got, report, tc.cmd.Output); err != nil {
+				t.Fatalf("writeReport(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got.String()); diff != "" {
+				t.Errorf("%s\nvalidate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
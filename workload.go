@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/response"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	karpenterv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+// Overheads Karpenter estimates for AWS nodes when it decides whether pods
+// fit an instance type, absent kubelet configuration in the EC2NodeClass.
+const (
+	vmMemoryOverheadPercent = 0.075
+	evictionMemoryMiB       = 100
+	kubeReservedMemoryMiB   = 255
+	kubeReservedMiBPerPod   = 11
+	evictionStoragePercent  = 0.1
+	defaultMaxPods          = 110
+)
+
+// defaultRootVolume is the root volume of Karpenter's default EC2NodeClass
+// block device mappings, and kubeReservedStorage the part of it kubelet
+// reserves.
+var (
+	defaultRootVolume   = k8sresource.MustParse("20Gi")
+	kubeReservedStorage = k8sresource.MustParse("1Gi")
+)
+
+// closestCandidates is how many instance types the Function reports when none
+// fit the Input's workload.
+const closestCandidates = 3
+
+// An instanceType is what the Function knows of an instance type.
+type instanceType struct {
+	Name      string
+	VCPUs     int64
+	MemoryMiB int64
+	GPUs      int64
+	MaxPods   int64
+}
+
+// An instanceTypeLister describes the instance types a provider offers.
+type instanceTypeLister interface {
+	// InstanceTypes returns the supplied instance types offered in the
+	// supplied region, ordered by name.
+	InstanceTypes(ctx context.Context, region string, names []string) ([]instanceType, error)
+}
+
+// maxDescribedInstanceTypes is how many instance types one DescribeInstanceTypes
+// call may name.
+const maxDescribedInstanceTypes = 100
+
+// InstanceTypes returns the supplied instance types offered in the supplied
+// region, ordered by name. It describes them maxDescribedInstanceTypes at a
+// time.
+func (p *awsProvider) InstanceTypes(ctx context.Context, region string, names []string) ([]instanceType, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ec2Client, err := p.client(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeInstanceTypesOutput{}
+	for chunk := range slices.Chunk(names, maxDescribedInstanceTypes) {
+		params := &ec2.DescribeInstanceTypesInput{InstanceTypes: make([]types.InstanceType, len(chunk))}
+		for i, name := range chunk {
+			params.InstanceTypes[i] = types.InstanceType(name)
+		}
+		described, err := describeInstanceTypes(ctx, ec2Client, params)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to describe instance types")
+		}
+		out.InstanceTypes = append(out.InstanceTypes, described.InstanceTypes...)
+	}
+	its := make([]instanceType, 0, len(out.InstanceTypes))
+	for _, info := range out.InstanceTypes {
+		it := instanceType{Name: string(info.InstanceType), MaxPods: defaultMaxPods}
+		if info.VCpuInfo != nil {
+			it.VCPUs = int64(aws.ToInt32(info.VCpuInfo.DefaultVCpus))
+		}
+		if info.MemoryInfo != nil {
+			it.MemoryMiB = aws.ToInt64(info.MemoryInfo.SizeInMiB)
+		}
+		if info.GpuInfo != nil {
+			for _, g := range info.GpuInfo.Gpus {
+				it.GPUs += int64(aws.ToInt32(g.Count))
+			}
+		}
+		if n := info.NetworkInfo; n != nil && aws.ToInt32(n.MaximumNetworkInterfaces) > 0 && aws.ToInt32(n.Ipv4AddressesPerInterface) > 0 {
+			// The VPC CNI gives each pod an address of a secondary ENI IP,
+			// except host network pods like kube-proxy and the CNI itself.
+			it.MaxPods = int64(aws.ToInt32(n.MaximumNetworkInterfaces))*(int64(aws.ToInt32(n.Ipv4AddressesPerInterface))-1) + 2
+		}
+		its = append(its, it)
+	}
+	sort.Slice(its, func(i, j int) bool { return its[i].Name < its[j].Name })
+	p.log.Debug("Found instance types", "region", region, "count", len(its))
+	return its, nil
+}
+
+// checkLaunchable returns an error if a NodePool of the supplied provider,
+// limited to the supplied offered instance types of the supplied categories,
+// or to the supplied selected instance types, can't launch a node of the
+// supplied shape requirements, or one the supplied workload fits. It warns if
+// the provider can't describe its instance types.
+func (f *Function) checkLaunchable(ctx context.Context, rsp *fnv1.RunFunctionResponse, prov nodeProvider, providerName string, w *v1beta1.Workload, shapeReqs []karpenterv1.NodeSelectorRequirementWithMinValues, region string, offered, categories []string, selected *instanceTypeSelection) error {
+	lister, ok := prov.(instanceTypeLister)
+	if !ok {
+		response.Warning(rsp, errors.Errorf("cannot check the Input's workload fits the instance types of provider %q", providerName)).TargetCompositeAndClaim()
+		return nil
+	}
+	// Describe only the instance types the NodePool may launch.
+	candidates := slices.DeleteFunc(slices.Clone(offered), func(t string) bool {
+		return !slices.Contains(categories, instanceCategory(t))
+	})
+	if selected != nil {
+		candidates = selected.Allowed
+	}
+	its, err := lister.InstanceTypes(ctx, region, candidates)
+	if err != nil {
+		return err
+	}
+	if len(shapeReqs) > 0 {
+		if len(its) == 0 {
+			return errors.Errorf("cannot check the Input's shape: no instance types of categories %v are described in %s", categories, region)
+		}
+		its = permittedInstanceTypes(its, categories, shapeReqs, prov.(shaper).ShapeLabels())
+		if len(its) == 0 {
+			return errors.Errorf("no instance types of categories %v in %s satisfy the Input's shape", categories, region)
+		}
+		f.log.Debug("Found instance types of the Input's shape", "region", region, "count", len(its))
+	}
+	if w == nil {
+		return nil
+	}
+	return checkWorkloadFit(w, its, region, categories)
+}
+
+// allocatable is what a node of an instance type has left for pods.
+type allocatable struct {
+	MilliCPU            int64
+	MemoryMiB           int64
+	GPUs                int64
+	EphemeralStorageMiB int64
+	Pods                int64
+}
+
+// allocatableOf returns what a node of the supplied instance type has left for
+// pods once Karpenter's estimates of the hypervisor's memory, kube-reserved
+// resources and kubelet's eviction thresholds are taken out.
+func allocatableOf(it instanceType) allocatable {
+	// kube-reserved CPU is 6% of the first core, 1% of the second, 0.5% of
+	// the third and fourth, and 0.25% of the rest.
+	var reservedMilliCPU float64
+	for i := int64(0); i < it.VCPUs; i++ {
+		switch {
+		case i == 0:
+			reservedMilliCPU += 60
+		case i == 1:
+			reservedMilliCPU += 10
+		case i < 4:
+			reservedMilliCPU += 5
+		default:
+			reservedMilliCPU += 2.5
+		}
+	}
+	vmOverheadMiB := int64(math.Ceil(float64(it.MemoryMiB) * vmMemoryOverheadPercent))
+	root := defaultRootVolume.Value()
+	storage := root - kubeReservedStorage.Value() - int64(math.Ceil(float64(root)*evictionStoragePercent))
+
+	return allocatable{
+		MilliCPU:            it.VCPUs*1000 - int64(math.Ceil(reservedMilliCPU)),
+		MemoryMiB:           it.MemoryMiB - vmOverheadMiB - (kubeReservedMemoryMiB + kubeReservedMiBPerPod*it.MaxPods) - evictionMemoryMiB,
+		GPUs:                it.GPUs,
+		EphemeralStorageMiB: storage / (1 << 20),
+		Pods:                it.MaxPods,
+	}
+}
+
+// A shortfall is how much of each resource a node lacks to run a workload.
+type shortfall struct {
+	instanceType string
+	missing      []string
+
+	// distance is the sum of the fraction of each resource the node lacks,
+	// so lower is closer to fitting.
+	distance float64
+}
+
+// String returns e.g. m5.large (short cpu 70m, memory 512Mi).
+func (s shortfall) String() string {
+	return fmt.Sprintf("%s (short %s)", s.instanceType, strings.Join(s.missing, ", "))
+}
+
+// shortfallOf returns how much of the supplied workload doesn't fit a node
+// with the supplied allocatable resources. It has no missing resources if the
+// workload fits.
+func shortfallOf(w *v1beta1.Workload, name string, a allocatable) shortfall {
+	s := shortfall{instanceType: name}
+	short := func(resource string, want, have int64, format func(int64) string) {
+		if want <= have {
+			return
+		}
+		s.missing = append(s.missing, resource+" "+format(want-have))
+		s.distance += float64(want-have) / float64(want)
+	}
+	mib := func(v int64) string { return fmt.Sprintf("%dMi", v) }
+	count := func(v int64) string { return fmt.Sprintf("%d", v) }
+
+	if w.CPU != nil {
+		short("cpu", w.CPU.MilliValue(), a.MilliCPU, func(v int64) string { return fmt.Sprintf("%dm", v) })
+	}
+	if w.Memory != nil {
+		short("memory", ceilMiB(*w.Memory), a.MemoryMiB, mib)
+	}
+	short("gpus", w.GPUs, a.GPUs, count)
+	if w.EphemeralStorage != nil {
+		short("ephemeral-storage", ceilMiB(*w.EphemeralStorage), a.EphemeralStorageMiB, mib)
+	}
+	short("pods", w.PodsPerNode, a.Pods, count)
+	return s
+}
+
+// ceilMiB returns the supplied quantity in MiB, rounded up.
+func ceilMiB(q k8sresource.Quantity) int64 {
+	return int64(math.Ceil(float64(q.Value()) / (1 << 20)))
+}
+
+// checkWorkloadFit returns an error if the supplied workload fits none of the
+// supplied instance types of the supplied categories, the instance types a
+// NodePool may launch. The error names the instance types that come closest.
+func checkWorkloadFit(w *v1beta1.Workload, its []instanceType, region string, categories []string) error {
+	var shortfalls []shortfall
+	for _, it := range its {
+		if !slices.Contains(categories, instanceCategory(it.Name)) {
+			continue
+		}
+		s := shortfallOf(w, it.Name, allocatableOf(it))
+		if len(s.missing) == 0 {
+			return nil
+		}
+		shortfalls = append(shortfalls, s)
+	}
+	if len(shortfalls) == 0 {
+		return errors.Errorf("cannot check the Input's workload fits: no instance types of categories %v are described in %s", categories, region)
+	}
+
+	sort.SliceStable(shortfalls, func(i, j int) bool { return shortfalls[i].distance < shortfalls[j].distance })
+	closest := make([]string, 0, closestCandidates)
+	for _, s := range shortfalls[:min(closestCandidates, len(shortfalls))] {
+		closest = append(closest, s.String())
+	}
+	return errors.Errorf("the Input's workload fits none of the instance types of categories %v in %s; closest are %s",
+		categories, region, strings.Join(closest, "; "))
+}
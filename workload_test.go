@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/google/go-cmp/cmp"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/crossplane/function-nodepools/input/v1beta1"
+)
+
+func TestAllocatableOf(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		it     instanceType
+		want   allocatable
+	}{
+		"Large": {
+			reason: "An m5.large should have what Karpenter estimates it has left for pods.",
+			it:     instanceType{Name: "m5.large", VCPUs: 2, MemoryMiB: 8192, MaxPods: 29},
+			want:   allocatable{MilliCPU: 1930, MemoryMiB: 6903, EphemeralStorageMiB: 17408, Pods: 29},
+		},
+		"ManyCores": {
+			reason: "Cores after the fourth should reserve 0.25% of a core each.",
+			it:     instanceType{Name: "g5.2xlarge", VCPUs: 8, MemoryMiB: 32768, GPUs: 1, MaxPods: 58},
+			want:   allocatable{MilliCPU: 7910, MemoryMiB: 29317, GPUs: 1, EphemeralStorageMiB: 17408, Pods: 58},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, allocatableOf(tc.it)); diff != "" {
+				t.Errorf("\n%s\nallocatableOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckWorkloadFit(t *testing.T) {
+	quantity := func(s string) *k8sresource.Quantity { q := k8sresource.MustParse(s); return &q }
+	its := []instanceType{
+		{Name: "c5.large", VCPUs: 2, MemoryMiB: 4096, MaxPods: 29},
+		{Name: "g5.2xlarge", VCPUs: 8, MemoryMiB: 32768, GPUs: 1, MaxPods: 58},
+		{Name: "m5.large", VCPUs: 2, MemoryMiB: 8192, MaxPods: 29},
+		{Name: "m5.xlarge", VCPUs: 4, MemoryMiB: 16384, MaxPods: 58},
+	}
+
+	cases := map[string]struct {
+		reason     string
+		w          *v1beta1.Workload
+		categories []string
+		want       string
+	}{
+		"Fits": {
+			reason:     "A workload that fits one of the instance types should pass.",
+			w:          &v1beta1.Workload{CPU: quantity("3"), Memory: quantity("12Gi"), PodsPerNode: 40},
+			categories: []string{"m", "c"},
+		},
+		"OtherCategory": {
+			reason:     "Instance types of categories the NodePool doesn't use shouldn't count.",
+			w:          &v1beta1.Workload{GPUs: 1},
+			categories: []string{"m", "c"},
+			want:       "the Input's workload fits none of the instance types of categories [m c] in us-east-1; closest are c5.large (short gpus 1); m5.large (short gpus 1); m5.xlarge (short gpus 1)",
+		},
+		"Closest": {
+			reason:     "The instance types that come closest to fitting should be reported first.",
+			w:          &v1beta1.Workload{CPU: quantity("2"), Memory: quantity("7Gi"), PodsPerNode: 59},
+			categories: []string{"m", "c"},
+			want:       "the Input's workload fits none of the instance types of categories [m c] in us-east-1; closest are m5.xlarge (short pods 1); m5.large (short cpu 70m, memory 265Mi, pods 30); c5.large (short cpu 70m, memory 4054Mi, pods 30)",
+		},
+		"NoInstanceTypes": {
+			reason:     "A workload can't be checked against categories with no instance types.",
+			w:          &v1beta1.Workload{CPU: quantity("1")},
+			categories: []string{"r"},
+			want:       "cannot check the Input's workload fits: no instance types of categories [r] are described in us-east-1",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			if err := checkWorkloadFit(tc.w, its, "us-east-1", tc.categories); err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckWorkloadFit(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// describedEC2 records how many instance types each DescribeInstanceTypes call
+// names.
+type describedEC2 struct {
+	ec2API
+	named *[]int
+}
+
+func (c describedEC2) DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	*c.named = append(*c.named, len(params.InstanceTypes))
+	return c.ec2API.DescribeInstanceTypes(ctx, params, optFns...)
+}
+
+func TestAWSInstanceTypes(t *testing.T) {
+	names := []string{"c5.large", "m5.large"}
+	for i := range 248 {
+		names = append(names, fmt.Sprintf("x%d.large", i))
+	}
+	cat := &catalog{
+		Regions: map[string]catalogRegion{"us-east-1": {InstanceTypes: append([]string{"m5.xlarge"}, names...)}},
+		InstanceTypes: map[string]catalogInstanceType{
+			"c5.large":  {VCPUs: 2, MemoryMiB: 4096},
+			"m5.large":  {VCPUs: 2, MemoryMiB: 8192, MaxNetworkInterfaces: 3, IPv4AddressesPerInterface: 10},
+			"m5.xlarge": {VCPUs: 4, MemoryMiB: 16384},
+		},
+	}
+
+	type want struct {
+		its   []instanceType
+		named []int
+	}
+	cases := map[string]struct {
+		reason string
+		names  []string
+		want   want
+	}{
+		"Chunked": {
+			reason: "Instance types should be described by name, at most 100 per call, and only the supplied ones returned.",
+			names:  names,
+			want: want{
+				its: []instanceType{
+					{Name: "c5.large", VCPUs: 2, MemoryMiB: 4096, MaxPods: defaultMaxPods},
+					{Name: "m5.large", VCPUs: 2, MemoryMiB: 8192, MaxPods: 29},
+				},
+				named: []int{100, 100, 50},
+			},
+		},
+		"None": {
+			reason: "No instance types should be described if none are supplied.",
+			want:   want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var named []int
+			p := &awsProvider{log: logging.NewNopLogger(), ec2: func(ctx context.Context, region string) (ec2API, error) {
+				c, err := cat.client(ctx, region)
+				return describedEC2{ec2API: c, named: &named}, err
+			}}
+			its, err := p.InstanceTypes(context.Background(), "us-east-1", tc.names)
+			if err != nil {
+				t.Fatalf("p.InstanceTypes(...): %v", err)
+			}
+			got := want{its: its, named: named}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\np.InstanceTypes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}